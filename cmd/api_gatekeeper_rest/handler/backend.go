@@ -1,73 +1,281 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/gustapinto/api-gatekeeper/cmd/api_gatekeeper_rest/middleware"
 	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
 	"github.com/gustapinto/api-gatekeeper/internal/service"
 	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
 )
 
 type Backend struct {
-	backendService service.Backend
+	backendService *service.Backend
+	logger         *slog.Logger
 }
 
-func NewBackend(backendService service.Backend) Backend {
+func NewBackend(backendService *service.Backend, logger *slog.Logger) Backend {
 	return Backend{
 		backendService: backendService,
+		logger:         logger,
 	}
 }
 
 func (b Backend) HandleBackendRouteRequest(w http.ResponseWriter, r *http.Request, backend config.Backend, route config.Route) {
-	userId := ""
-	if uidStr, ok := r.Context().Value("userId").(string); ok {
-		userId = uidStr
-	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	requestID := middleware.RequestIDFromContext(r.Context())
+	logger := b.logger.With("backend", backend.Name, "route", route.Name(), "requestId", requestID)
 
-	requestID := ""
-	if uidStr, ok := r.Context().Value("requestId").(string); ok {
-		requestID = uidStr
+	if route.IsAnyMethod() {
+		route.Method = strings.ToUpper(r.Method)
 	}
 
 	if strings.ToUpper(r.Method) != route.Method {
-		httputil.WriteMethodNotAllowed(w)
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodeUnimplemented, "method not allowed")
+			return
+		}
+
+		httputil.WriteMethodNotAllowed(w, r)
 		return
 	}
 
-	for _, variable := range route.PatternVariables() {
-		value := r.PathValue(variable.Name())
-		newPattern := variable.ReplaceFromPattern(route.BackendPath, value)
+	backendPath, queryParams, err := route.BuildBackendPath(r.URL.Path, r.PathValue, r.URL.Query())
+	if err != nil {
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodeInternal, "failed to build the backend path")
+			return
+		}
+
+		httputil.WriteBadRequest(w, r, err)
+		return
+	}
 
-		route.BackendPath = newPattern
+	route.BackendPath = backendPath
+	forwarding := middleware.ForwardingFromContext(r.Context())
+	clientIP := forwarding.ClientIP
+	templateData := config.HeaderTemplateData{
+		User:      user,
+		RequestID: requestID,
+		ClientIP:  clientIP,
 	}
 
 	response, err := b.backendService.DoRequestToBackendRoute(
-		userId,
-		requestID,
+		r.Context(),
 		backend,
 		route,
-		r.Body,
-		httputil.GetHeadersAsMap(r),
-		httputil.GetQueryParamsAsMap(r))
+		model.BackendRequestParams{
+			User:             user,
+			RequestID:        requestID,
+			ClientIP:         clientIP,
+			ForwardedHeaders: forwarding.Headers(),
+			Body:             r.Body,
+			ContentLength:    r.ContentLength,
+			Trailer:          r.Trailer,
+			Headers:          r.Header,
+			QueryParams:      queryParams,
+		})
 	if err != nil {
-		httputil.WriteInternalServerError(w, err)
+		b.writeUpstreamError(w, r, logger, backend, route, err)
 		return
 	}
 	defer response.Body.Close()
 
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		httputil.WriteInternalServerError(w, err)
+	if response.StatusCode == http.StatusSwitchingProtocols && route.Upgrade.Enabled {
+		b.handleSwitchingProtocols(w, r, logger, backend, route, response, templateData)
 		return
 	}
 
-	responseContentType := response.Header.Get("Content-Type")
-	if responseContentType != "" {
-		w.Header().Add("Content-Type", responseContentType)
+	responseHeaders := response.Header.Clone()
+	httputil.RemoveHopByHopHeaders(responseHeaders)
+	b.applyResponseHeaders(logger, backend, route, responseHeaders, response.Header, templateData)
+
+	if middleware.HasCORSPolicy(r.Context()) {
+		httputil.RemoveCORSHeaders(responseHeaders)
+	}
+
+	// Vary is a list both the gatekeeper, for CORS, and the backend contribute to, so it is merged
+	gatekeeperVary := w.Header().Values("Vary")
+	httputil.CopyHeaders(w.Header(), responseHeaders)
+	if len(gatekeeperVary) > 0 && len(responseHeaders.Values("Vary")) > 0 {
+		w.Header()["Vary"] = append(gatekeeperVary, responseHeaders.Values("Vary")...)
+	}
+
+	// The declared trailers must be announced before the headers are written, their values
+	// are only known after the body is fully read
+	announcedTrailers := make(map[string]bool, len(response.Trailer))
+	for key := range response.Trailer {
+		w.Header().Add("Trailer", key)
+		announcedTrailers[key] = true
 	}
 
 	w.WriteHeader(response.StatusCode)
-	w.Write(responseBody)
+
+	// The headers are already sent at this point, so a failed copy (usually a client
+	// disconnect) can only be dropped
+	httputil.StreamBody(w, response.Body)
+
+	// Trailers the backend did not declare, like the grpc-status of most HTTP/2 servers, only
+	// show up after the body is read, so they are sent with the undeclared trailer prefix
+	for key, values := range response.Trailer {
+		if announcedTrailers[key] {
+			w.Header()[key] = values
+		} else {
+			w.Header()[http.TrailerPrefix+key] = values
+		}
+	}
+}
+
+// writeUpstreamError Writes the error of a failed backend exchange, the error details are only
+// logged, as they may contain internal hostnames and addresses
+func (b Backend) writeUpstreamError(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger *slog.Logger,
+	backend config.Backend,
+	route config.Route,
+	err error,
+) {
+	var upstreamErr *service.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		logger.Error("Failed to proxy request", "error", err)
+
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodeInternal, "internal error")
+			return
+		}
+
+		httputil.WriteGatewayError(w, r, http.StatusInternalServerError, httputil.ProblemTypeBlank, "Internal server error")
+		return
+	}
+
+	if upstreamErr.Kind == service.UpstreamErrorCanceled {
+		logger.Info("Client closed the request before the backend responded")
+		return
+	}
+
+	logger.Error("Upstream request failed", "kind", upstreamErr.Kind, "error", upstreamErr.Err)
+
+	if route.IsGRPC() {
+		b.writeGRPCUpstreamError(w, upstreamErr)
+		return
+	}
+
+	if upstreamErr.RetryAfter > 0 {
+		retryAfterSeconds := int(math.Ceil(upstreamErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	}
+
+	statusCode := upstreamErr.StatusCode()
+	if errorBody, exists := backend.ErrorBodies[statusCode]; exists {
+		body, err := errorBody.Render(config.ErrorBodyTemplateData{
+			Status:    statusCode,
+			Message:   upstreamErr.Message(),
+			RequestID: middleware.RequestIDFromContext(r.Context()),
+		})
+		if err == nil {
+			w.Header().Set("Content-Type", errorBody.ContentType)
+			w.WriteHeader(statusCode)
+			w.Write(body)
+			return
+		}
+
+		logger.Error("Failed to render the backend error body", "status", statusCode, "error", err)
+	}
+
+	httputil.WriteGatewayError(w, r, statusCode, "urn:api-gatekeeper:problem:upstream-"+string(upstreamErr.Kind), upstreamErr.Message())
+}
+
+func (Backend) writeGRPCUpstreamError(w http.ResponseWriter, upstreamErr *service.UpstreamError) {
+	switch upstreamErr.Kind {
+	case service.UpstreamErrorUnavailable:
+		httputil.WriteGRPCError(w, httputil.GRPCCodeUnavailable, "upstream unavailable")
+	case service.UpstreamErrorTimeout:
+		httputil.WriteGRPCError(w, httputil.GRPCCodeDeadlineExceeded, "upstream timeout")
+	default:
+		httputil.WriteGRPCError(w, httputil.GRPCCodeUnavailable, "upstream request failed")
+	}
+}
+
+// applyResponseHeaders Applies the backend and route response headers rules, in this order, to the
+// headers that will be sent to the client
+func (Backend) applyResponseHeaders(
+	logger *slog.Logger,
+	backend config.Backend,
+	route config.Route,
+	headers http.Header,
+	upstreamHeaders http.Header,
+	templateData config.HeaderTemplateData,
+) {
+	backend.ResponseHeaders.Apply(headers)
+	route.ResponseHeaders.Apply(headers)
+
+	for _, rules := range []config.HeaderRules{backend.ResponseHeaderRules, route.ResponseHeaderRules} {
+		if err := rules.Apply(headers, upstreamHeaders, templateData); err != nil {
+			logger.Error("Failed to apply the response header rules", "error", err)
+		}
+	}
+}
+
+func (b Backend) handleSwitchingProtocols(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger *slog.Logger,
+	backend config.Backend,
+	route config.Route,
+	response *http.Response,
+	templateData config.HeaderTemplateData,
+) {
+	upstreamConn, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		logger.Error("Backend switched protocols without an upgradable connection")
+		httputil.WriteInternalServerError(w, r)
+		return
+	}
+
+	clientConn, clientBuffer, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		logger.Error("Failed to hijack the client connection", "error", err)
+		httputil.WriteInternalServerError(w, r)
+		return
+	}
+	defer clientConn.Close()
+
+	// The Connection and Upgrade hop-by-hop headers are kept, as they are part of the handshake
+	responseHeaders := response.Header.Clone()
+	b.applyResponseHeaders(logger, backend, route, responseHeaders, response.Header, templateData)
+
+	fmt.Fprintf(clientBuffer, "HTTP/1.1 %d %s\r\n", response.StatusCode, http.StatusText(response.StatusCode))
+	responseHeaders.Write(clientBuffer)
+	clientBuffer.WriteString("\r\n")
+
+	if err := clientBuffer.Flush(); err != nil {
+		return
+	}
+
+	stats := b.backendService.Tunnel(backend, clientConn, clientBuffer.Reader, upstreamConn, route.Upgrade.IdleTimeout())
+
+	logger.Info(
+		"Upgraded connection closed",
+		"protocol", response.Header.Get("Upgrade"),
+		"bytesFromClient", stats.BytesFromClient,
+		"bytesToClient", stats.BytesToClient,
+		"duration", stats.Duration)
+}
+
+func (b Backend) GetHealth(w http.ResponseWriter, r *http.Request) {
+	httputil.WriteOk(w, b.backendService.Health())
+}
+
+func (b Backend) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	httputil.WriteOk(w, b.backendService.JWKS())
 }
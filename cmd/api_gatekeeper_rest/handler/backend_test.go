@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+func TestHandleBackendRouteRequestForwardsUndeclaredTrailers(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "X-Declared")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("payload"))
+
+		w.Header().Set("X-Declared", "declared")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	backend := config.Backend{
+		Name:     "grpc",
+		Host:     server.URL,
+		Protocol: config.BackendProtocolH2C,
+		Routes: []config.Route{
+			{Type: config.RouteTypeGRPC, BackendPath: "/pkg.Service/Method"},
+		},
+	}
+	if err := backend.ValidateAndNormalize(); err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+
+	handler := NewBackend(service.NewBackend([]config.Backend{backend}, nil, ""), slog.New(slog.DiscardHandler))
+	request := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", strings.NewReader("request"))
+	request.Header.Set("Content-Type", "application/grpc")
+	recorder := httptest.NewRecorder()
+
+	handler.HandleBackendRouteRequest(recorder, request, backend, backend.Routes[0])
+
+	response := recorder.Result()
+	body, _ := io.ReadAll(response.Body)
+	if string(body) != "payload" {
+		t.Errorf("expected the backend body, got %q", body)
+	}
+
+	expectedTrailers := map[string]string{
+		"X-Declared":   "declared",
+		"Grpc-Status":  "0",
+		"Grpc-Message": "ok",
+	}
+	for key, expected := range expectedTrailers {
+		if got := response.Trailer.Get(key); got != expected {
+			t.Errorf("expected trailer %s=%q, got %q", key, expected, got)
+		}
+	}
+}
+
+func TestHandleBackendRouteRequestReplacesMiddlewareHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+		w.Header().Set("Vary", "Accept-Encoding")
+	}))
+	defer server.Close()
+
+	backend := config.Backend{
+		Name:   "items",
+		Host:   server.URL,
+		Routes: []config.Route{{Method: http.MethodGet, GatekeeperPath: "/items", BackendPath: "/items"}},
+	}
+	if err := backend.ValidateAndNormalize(); err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+
+	handler := NewBackend(service.NewBackend([]config.Backend{backend}, nil, "X-Request-Id"), slog.New(slog.DiscardHandler))
+	request := httptest.NewRequest(http.MethodGet, "/items", nil)
+	request = request.WithContext(httputil.WithRequestID(request.Context(), "request-id"))
+	recorder := httptest.NewRecorder()
+
+	// Set by the RequestID and CORS middlewares before the backend is called
+	recorder.Header().Set("X-Request-Id", "request-id")
+	recorder.Header().Set("Vary", "Origin")
+
+	handler.HandleBackendRouteRequest(recorder, request, backend, backend.Routes[0])
+
+	if got := recorder.Header().Values("X-Request-Id"); len(got) != 1 || got[0] != "request-id" {
+		t.Errorf("expected a single request ID, got %v", got)
+	}
+
+	if got := recorder.Header().Values("Vary"); !slices.Equal(got, []string{"Origin", "Accept-Encoding"}) {
+		t.Errorf("expected the Vary values to be merged, got %v", got)
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+// writeServiceError Writes a service error with the status of its domain error, errors without
+// a domain are logged and written as generic internal errors, so their causes are not exposed
+func writeServiceError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	var validationErr *service.ValidationError
+
+	switch {
+	case errors.As(err, &validationErr):
+		httputil.WriteBadRequest(w, r, err)
+	case errors.Is(err, service.ErrUnauthenticated):
+		httputil.WriteUnauthorized(w, r)
+	case errors.Is(err, service.ErrNotFound):
+		httputil.WriteNotFound(w, r, err)
+	case errors.Is(err, service.ErrConflict):
+		httputil.WriteConflict(w, r, err)
+	case errors.Is(err, service.ErrUnprocessable):
+		httputil.WriteUnprocessableEntity(w, r, err)
+	default:
+		logger.Error("Failed to handle the request", "path", r.URL.Path, "error", err)
+		httputil.WriteInternalServerError(w, r)
+	}
+}
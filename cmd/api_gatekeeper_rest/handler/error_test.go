@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+)
+
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{"validation", &service.ValidationError{Message: "login parameter must be present"}, http.StatusBadRequest, "login parameter must be present"},
+		{"wrapped validation", fmt.Errorf("create: %w", &service.ValidationError{Message: "bad"}), http.StatusBadRequest, "create: bad"},
+		{"unauthenticated", service.ErrUnauthenticated, http.StatusUnauthorized, ""},
+		{"not found", fmt.Errorf("user %w", service.ErrNotFound), http.StatusNotFound, "user not found"},
+		{"conflict", fmt.Errorf("user %w", service.ErrConflict), http.StatusConflict, "user already exists"},
+		{"unprocessable", fmt.Errorf("quota is %w", service.ErrUnprocessable), http.StatusUnprocessableEntity, "quota is unprocessable"},
+		{"other", errors.New("dial tcp 10.0.0.5:5432: connection refused"), http.StatusInternalServerError, "An internal error occurred while handling the request"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api-gatekeeper/v1/users", nil)
+
+			writeServiceError(w, r, slog.New(slog.DiscardHandler), test.err)
+
+			if w.Code != test.wantStatus {
+				t.Errorf("expected status %d, got %d", test.wantStatus, w.Code)
+			}
+
+			var problem map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("expected a JSON body, got %q", w.Body.String())
+			}
+
+			if detail, _ := problem["detail"].(string); detail != test.wantDetail {
+				t.Errorf("expected detail %q, got %q", test.wantDetail, detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+type Quota struct {
+	quotaService *service.Quota
+	logger       *slog.Logger
+}
+
+func NewQuota(quotaService *service.Quota, logger *slog.Logger) Quota {
+	return Quota{
+		quotaService: quotaService,
+		logger:       logger,
+	}
+}
+
+func (q Quota) GetByUser(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	usages, err := q.quotaService.GetByUser(r.Context(), userId)
+	if err != nil {
+		writeServiceError(w, r, q.logger, err)
+		return
+	}
+
+	httputil.WriteOk(w, usages)
+}
+
+func (q Quota) Reset(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	quotaName := r.PathValue("quotaName")
+
+	if err := q.quotaService.Reset(r.Context(), userId, quotaName); err != nil {
+		writeServiceError(w, r, q.logger, err)
+		return
+	}
+
+	httputil.WriteNoContent(w)
+}
+
+func (q Quota) ResetAll(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	if err := q.quotaService.ResetAll(r.Context(), userId); err != nil {
+		writeServiceError(w, r, q.logger, err)
+		return
+	}
+
+	httputil.WriteNoContent(w)
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -15,30 +16,27 @@ import (
 type User struct {
 	userService *service.User
 	jwtService  *service.JWT
+	logger      *slog.Logger
 }
 
-func NewUser(userService *service.User, jwtService *service.JWT) User {
+func NewUser(userService *service.User, jwtService *service.JWT, logger *slog.Logger) User {
 	return User{
 		userService: userService,
 		jwtService:  jwtService,
+		logger:      logger,
 	}
 }
 
 func (u User) Create(w http.ResponseWriter, r *http.Request) {
 	var req model.CreateUserParams
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httputil.WriteBadRequest(w, errors.New("failed to parse request body"))
+		httputil.WriteBadRequest(w, r, errors.New("failed to parse request body"))
 		return
 	}
 
 	user, err := u.userService.Create(req)
 	if err != nil {
-		if strings.Contains(err.Error(), "badparams:") {
-			httputil.WriteBadRequest(w, err)
-			return
-		}
-
-		httputil.WriteUnprocessableEntity(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -48,7 +46,7 @@ func (u User) Create(w http.ResponseWriter, r *http.Request) {
 func (u User) Update(w http.ResponseWriter, r *http.Request) {
 	var req model.UpdateUserParams
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httputil.WriteBadRequest(w, errors.New("failed to parse request body"))
+		httputil.WriteBadRequest(w, r, errors.New("failed to parse request body"))
 		return
 	}
 
@@ -56,12 +54,7 @@ func (u User) Update(w http.ResponseWriter, r *http.Request) {
 
 	user, err := u.userService.Update(req)
 	if err != nil {
-		if strings.Contains(err.Error(), "badparams:") {
-			httputil.WriteBadRequest(w, err)
-			return
-		}
-
-		httputil.WriteUnprocessableEntity(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -72,12 +65,7 @@ func (u User) Delete(w http.ResponseWriter, r *http.Request) {
 	userId := r.PathValue("userId")
 
 	if err := u.userService.Delete(userId); err != nil {
-		if strings.Contains(err.Error(), "badparams:") {
-			httputil.WriteBadRequest(w, err)
-			return
-		}
-
-		httputil.WriteUnprocessableEntity(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -89,12 +77,7 @@ func (u User) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	user, err := u.userService.GetByID(userId)
 	if err != nil {
-		if strings.Contains(err.Error(), "badparams:") {
-			httputil.WriteBadRequest(w, err)
-			return
-		}
-
-		httputil.WriteUnprocessableEntity(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -104,7 +87,7 @@ func (u User) GetByID(w http.ResponseWriter, r *http.Request) {
 func (u User) GetAll(w http.ResponseWriter, r *http.Request) {
 	user, err := u.userService.GetAll()
 	if err != nil {
-		httputil.WriteUnprocessableEntity(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -114,18 +97,18 @@ func (u User) GetAll(w http.ResponseWriter, r *http.Request) {
 func (u User) Login(w http.ResponseWriter, r *http.Request) {
 	username, password, err := httputil.ParseBasicAuthorizationToken(r.Header.Get("Authorization"))
 	if err != nil {
-		if strings.Contains(err.Error(), "badparams:") {
-			httputil.WriteBadRequest(w, err)
+		if errors.Is(err, httputil.ErrMissingAuthorizationToken) {
+			httputil.WriteBadRequest(w, r, err)
 			return
 		}
 
-		httputil.WriteUnauthorized(w)
+		httputil.WriteUnauthorized(w, r)
 		return
 	}
 
 	user, err := u.userService.Login(username, password)
 	if err != nil {
-		httputil.WriteBadRequest(w, err)
+		writeServiceError(w, r, u.logger, err)
 		return
 	}
 
@@ -133,7 +116,7 @@ func (u User) Login(w http.ResponseWriter, r *http.Request) {
 	if strings.ToLower(strings.TrimSpace(tokenType)) == "jwt" {
 		token, err := u.jwtService.GenerateToken(user)
 		if err != nil {
-			httputil.WriteBadRequest(w, err)
+			httputil.WriteBadRequest(w, r, err)
 			return
 		}
 
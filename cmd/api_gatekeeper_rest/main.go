@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gustapinto/api-gatekeeper/cmd/api_gatekeeper_rest/handler"
 	"github.com/gustapinto/api-gatekeeper/cmd/api_gatekeeper_rest/middleware"
 	"github.com/gustapinto/api-gatekeeper/internal/config"
 	"github.com/gustapinto/api-gatekeeper/internal/repository/gorm"
+	"github.com/gustapinto/api-gatekeeper/internal/repository/memory"
 	"github.com/gustapinto/api-gatekeeper/internal/service"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
 )
 
 func main() {
@@ -37,6 +43,8 @@ func main() {
 
 	logger.Info("Validated application config")
 
+	httputil.UseLegacyErrors(cfg.API.ErrorFormat == config.ErrorFormatLegacy)
+
 	db, err := gorm.OpenDatabaseConnection(cfg.Database)
 	if err != nil {
 		logger.Error("Failed to connect to database", "error", err)
@@ -49,11 +57,41 @@ func main() {
 	basicAuthService := service.NewBasicAuth(userRepository)
 	jwtService := service.NewJWT(userRepository, cfg.API.JwtSecret, cfg.API.TokenDuration())
 	userService := service.NewUser(userRepository)
-	userHandler := handler.NewUser(userService, jwtService)
-	backendService := service.NewBackend()
-	backendHandler := handler.NewBackend(backendService)
+	userHandler := handler.NewUser(userService, jwtService, logger)
+	identityAssertion, err := service.NewIdentityAssertion(cfg.API.IdentityAssertion)
+	if err != nil {
+		logger.Error("Failed to load identity assertion key", "error", err)
+		os.Exit(1)
+	}
+
+	if identityAssertion.IsEphemeral() {
+		logger.Warn("No identity assertion private key configured, using an ephemeral key")
+	}
+
+	backendService := service.NewBackend(cfg.Backends, identityAssertion, cfg.API.RequestIDHeader)
+	backendHandler := handler.NewBackend(backendService, logger)
+
+	// The quotas span long periods, so their counters are always kept in the database
+	databaseLimiter := gorm.NewLimiter(db)
+	quotaService := service.NewQuota(cfg.Quotas, databaseLimiter, userRepository)
+	quotaHandler := handler.NewQuota(quotaService, logger)
 
-	backends := append(cfg.Backends, config.Backend{}.APIGatekeeperBackend(userHandler))
+	var limiterStore service.LimiterStore
+	switch cfg.API.LimiterStore {
+	case config.LimiterStoreMemory:
+		limiterStore = memory.NewLimiter()
+	case config.LimiterStoreDatabase:
+		limiterStore = databaseLimiter
+	}
+
+	rateLimiter := service.NewRateLimiter(limiterStore)
+
+	backends := append(cfg.Backends, config.Backend{}.APIGatekeeperBackend(
+		userHandler,
+		quotaHandler,
+		backendHandler,
+		cfg.API.ManagementIPAllow,
+		cfg.API.ApplicationRateLimits))
 
 	logger.Info("Created dependencies")
 
@@ -81,9 +119,15 @@ func main() {
 	}
 
 	auth := middleware.NewAuth(authService)
+	rateLimit := middleware.NewRateLimit(rateLimiter, logger)
+	quota := middleware.NewQuota(quotaService, logger)
+	requestID := middleware.NewRequestID(cfg.API.RequestIDHeader)
+	forwarded := middleware.NewForwarded(cfg.API.TrustedProxyPrefixes())
+	ipFilter := middleware.NewIPFilter(cfg.API.IPFilter())
+	cors := middleware.NewCORS(cfg.API.CORS)
 
 	mux := http.NewServeMux()
-	alreadyRegisteredRoutes := make(map[string]bool)
+	registeredRoutes := make(map[string]middleware.RegisteredRoute)
 	for _, backend := range backends {
 		backendLogger := logger.With("backend", backend.Name)
 
@@ -91,27 +135,50 @@ func main() {
 			routeLogger := backendLogger.With("route", route.Name())
 			routePattern := route.Pattern()
 
-			if _, exists := alreadyRegisteredRoutes[routePattern]; exists {
+			if _, exists := registeredRoutes[routePattern]; exists {
 				routeLogger.Warn("Route already registered, skipping")
 				continue
 			}
 
 			mux.HandleFunc(routePattern, func(w http.ResponseWriter, r *http.Request) {
-				start := time.Now()
-
-				if route.IsApplicationRoute() {
-					auth.GuardApplicationRoute(w, r, backend, route, route.HandlerFunc)
-				} else {
-					auth.GuardBackendRoute(w, r, backend, route, backendHandler.HandleBackendRouteRequest)
-				}
-
-				requestDuration := time.Since(start)
-				routeLogger.Info("Request processed", "timeTaken", requestDuration)
+				requestID.Handle(w, r, func(w http.ResponseWriter, r *http.Request) {
+					forwarded.Handle(w, r, func(w http.ResponseWriter, r *http.Request) {
+						start := time.Now()
+
+						cors.GuardRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+							ipFilter.GuardRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+								rateLimit.GuardRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+									if route.IsApplicationRoute() {
+										auth.GuardApplicationRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+											rateLimit.GuardApplicationRoute(w, r, backend, route, route.HandlerFunc)
+										})
+									} else {
+										auth.GuardBackendRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request, backend config.Backend, route config.Route) {
+											rateLimit.GuardBackendRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request, backend config.Backend, route config.Route) {
+												quota.GuardBackendRoute(w, r, backend, route, backendHandler.HandleBackendRouteRequest)
+											})
+										})
+									}
+								})
+							})
+						})
+
+						requestDuration := time.Since(start)
+						routeLogger.Info(
+							"Request processed",
+							"requestId", middleware.RequestIDFromContext(r.Context()),
+							"clientIp", middleware.ClientIPFromContext(r.Context()),
+							"timeTaken", requestDuration)
+					})
+				})
 			})
 
 			routeLogger.Info("Route registered", "method", route.Method, "path", route.GatekeeperPath)
 
-			alreadyRegisteredRoutes[routePattern] = true
+			registeredRoutes[routePattern] = middleware.RegisteredRoute{
+				Backend: backend,
+				Route:   route,
+			}
 		}
 	}
 
@@ -128,9 +195,48 @@ func main() {
 
 	logger.Info("Application started", "timeTaken", startupDuration, "address", address)
 
-	err = http.Serve(listener, mux)
-	if err != nil {
+	server := &http.Server{
+		Handler: cors.Handler(mux, registeredRoutes),
+	}
+
+	if cfg.API.H2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	backendService.StartHealthChecks(ctx, logger)
+
+	databaseLimiter.StartCleanup(ctx, logger)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+
+		<-ctx.Done()
+
+		logger.Info("Shutting down application")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Failed to gracefully shutdown server", "error", err.Error())
+		}
+	}()
+
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("Failed to server", "address", address, "error", err.Error())
 		os.Exit(1)
 	}
+
+	<-shutdownDone
+
+	backendService.Close()
+
+	logger.Info("Application stopped")
 }
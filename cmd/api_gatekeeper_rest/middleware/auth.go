@@ -24,8 +24,6 @@ func (a Auth) GuardBackendRoute(
 	route config.Route,
 	next GuardBackendRouteNextFunc,
 ) {
-	requestID := getRequestId(r)
-
 	if route.IsPublic {
 		next(w, r, backend, route)
 		return
@@ -33,17 +31,27 @@ func (a Auth) GuardBackendRoute(
 
 	user, err := a.authService.AuthenticateToken(r.Header.Get("Authorization"))
 	if err != nil {
-		httputil.WriteUnauthorized(w)
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodeUnauthenticated, "unauthenticated")
+			return
+		}
+
+		httputil.WriteUnauthorized(w, r)
 		return
 	}
 
 	if err := a.authService.Authorize(user, mergeScopes(backend, route)); err != nil {
-		httputil.WriteForbidden(w)
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodePermissionDenied, err.Error())
+			return
+		}
+
+		httputil.WriteForbidden(w, r, missingScopes(err))
 		return
 	}
 
 	ctx := withUserID(r.Context(), user.ID)
-	ctx = withRequestID(ctx, requestID)
+	ctx = withUser(ctx, user)
 
 	next(w, r.WithContext(ctx), backend, route)
 }
@@ -55,8 +63,6 @@ func (a Auth) GuardApplicationRoute(
 	route config.Route,
 	next GuardApplicationRouteNextFunc,
 ) {
-	requestID := getRequestId(r)
-
 	if route.IsPublic {
 		next(w, r)
 		return
@@ -64,17 +70,17 @@ func (a Auth) GuardApplicationRoute(
 
 	user, err := a.authService.AuthenticateToken(r.Header.Get("Authorization"))
 	if err != nil {
-		httputil.WriteUnauthorized(w)
+		httputil.WriteUnauthorized(w, r)
 		return
 	}
 
 	if err := a.authService.Authorize(user, mergeScopes(backend, route)); err != nil {
-		httputil.WriteForbidden(w)
+		httputil.WriteForbidden(w, r, missingScopes(err))
 		return
 	}
 
 	ctx := withUserID(r.Context(), user.ID)
-	ctx = withRequestID(ctx, requestID)
+	ctx = withUser(ctx, user)
 
 	next(w, r.WithContext(ctx))
 }
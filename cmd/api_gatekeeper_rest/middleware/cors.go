@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+// RegisteredRoute Is a route registered in the mux, indexed by its pattern
+type RegisteredRoute struct {
+	Backend config.Backend
+	Route   config.Route
+}
+
+type CORS struct {
+	apiPolicy config.CORS
+}
+
+func NewCORS(apiPolicy config.CORS) CORS {
+	return CORS{
+		apiPolicy: apiPolicy,
+	}
+}
+
+// Handler Answers the CORS preflights of the registered routes before they reach the mux, as
+// the mux would reject the OPTIONS method. The preflight route is the one the mux resolves for
+// the requested method, preflights of routes without a CORS policy are served by the mux
+func (m CORS) Handler(mux *http.ServeMux, routes map[string]RegisteredRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isPreflightRequest(r) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		preflight := r.Clone(r.Context())
+		preflight.Method = r.Header.Get("Access-Control-Request-Method")
+
+		_, pattern := mux.Handler(preflight)
+		registered, exists := routes[pattern]
+		if !exists {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		policy := m.policy(registered.Backend, registered.Route)
+		if !policy.IsEnabled() {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		m.writePreflight(w, r, policy)
+	})
+}
+
+// GuardRoute Adds the CORS headers of the route policy to the response, requests from
+// disallowed origins are still served, but without the headers the browser blocks them
+func (m CORS) GuardRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next http.HandlerFunc,
+) {
+	policy := m.policy(backend, route)
+	if !policy.IsEnabled() {
+		next(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	if origin := r.Header.Get("Origin"); policy.AllowsOrigin(origin) {
+		m.setAllowOrigin(header, policy, origin)
+
+		if len(policy.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+		}
+	}
+
+	next(w, r.WithContext(withCORSPolicy(r.Context())))
+}
+
+// policy Returns the most specific CORS policy of the route, the route one replaces the
+// backend one, which replaces the API one
+func (m CORS) policy(backend config.Backend, route config.Route) config.CORS {
+	if route.CORS.IsEnabled() {
+		return route.CORS
+	}
+
+	if backend.CORS.IsEnabled() {
+		return backend.CORS
+	}
+
+	return m.apiPolicy
+}
+
+// writePreflight Answers the preflight with 204, the CORS headers are only present if the
+// origin, method and headers are allowed
+func (m CORS) writePreflight(w http.ResponseWriter, r *http.Request, policy config.CORS) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	requestedHeaders := preflightRequestedHeaders(r)
+
+	allowed := policy.AllowsOrigin(origin) && policy.AllowsMethod(method)
+	for _, requestedHeader := range requestedHeaders {
+		allowed = allowed && policy.AllowsHeader(requestedHeader)
+	}
+
+	if allowed {
+		m.setAllowOrigin(header, policy, origin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+
+		// The requested headers are all allowed at this point, so they are echoed back, which
+		// also covers the * wildcard, as browsers ignore it in credentialed requests
+		if len(requestedHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+		}
+
+		if policy.MaxAgeSeconds > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAgeSeconds))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAllowOrigin Allows the origin, the * wildcard is only sent without credentials, as
+// browsers reject it in credentialed requests
+func (CORS) setAllowOrigin(header http.Header, policy config.CORS, origin string) {
+	if policy.AllowsAnyOrigin() && !policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func isPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func preflightRequestedHeaders(r *http.Request) []string {
+	requestedHeaders := make([]string, 0)
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, requestedHeader := range strings.Split(value, ",") {
+			if requestedHeader = strings.TrimSpace(requestedHeader); requestedHeader != "" {
+				requestedHeaders = append(requestedHeaders, strings.ToLower(requestedHeader))
+			}
+		}
+	}
+
+	return requestedHeaders
+}
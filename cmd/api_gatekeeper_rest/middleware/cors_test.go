@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+func newTestCORSPolicy(t *testing.T, policy config.CORS) config.CORS {
+	t.Helper()
+
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+	policy.Normalize()
+
+	return policy
+}
+
+func TestCORSPolicyPrecedence(t *testing.T) {
+	apiPolicy := newTestCORSPolicy(t, config.CORS{AllowedOrigins: []string{"https://api.example.com"}})
+	backendPolicy := newTestCORSPolicy(t, config.CORS{AllowedOrigins: []string{"https://backend.example.com"}})
+	routePolicy := newTestCORSPolicy(t, config.CORS{AllowedOrigins: []string{"https://route.example.com"}})
+	cors := NewCORS(apiPolicy)
+
+	tests := []struct {
+		name    string
+		backend config.Backend
+		route   config.Route
+		want    string
+	}{
+		{"api", config.Backend{}, config.Route{}, "https://api.example.com"},
+		{"backend replaces api", config.Backend{CORS: backendPolicy}, config.Route{}, "https://backend.example.com"},
+		{"route replaces backend", config.Backend{CORS: backendPolicy}, config.Route{CORS: routePolicy}, "https://route.example.com"},
+		{"route replaces api", config.Backend{}, config.Route{CORS: routePolicy}, "https://route.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := cors.policy(test.backend, test.route)
+			if len(policy.AllowedOrigins) != 1 || policy.AllowedOrigins[0] != test.want {
+				t.Errorf("expected the %s policy, got %v", test.want, policy.AllowedOrigins)
+			}
+		})
+	}
+}
+
+func TestCORSHandlerAnswersPreflights(t *testing.T) {
+	apiPolicy := newTestCORSPolicy(t, config.CORS{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAgeSeconds:  600,
+	})
+	credentialsPolicy := newTestCORSPolicy(t, config.CORS{
+		AllowedOrigins:   []string{`regex:^https://partner-[a-z]+\.example\.org$`},
+		AllowedMethods:   []string{"PUT"},
+		AllowCredentials: true,
+	})
+
+	backend := config.Backend{Name: "items"}
+	listRoute := config.Route{Method: http.MethodGet, GatekeeperPath: "/items"}
+	updateRoute := config.Route{Method: http.MethodPut, GatekeeperPath: "/items/{id}", CORS: credentialsPolicy}
+	otherBackend := config.Backend{Name: "other"}
+	otherRoute := config.Route{Method: http.MethodPost, GatekeeperPath: "/items/{id}"}
+
+	mux := http.NewServeMux()
+	routes := make(map[string]RegisteredRoute)
+	for _, registered := range []RegisteredRoute{
+		{Backend: backend, Route: listRoute},
+		{Backend: backend, Route: updateRoute},
+		{Backend: otherBackend, Route: otherRoute},
+	} {
+		pattern := registered.Route.Pattern()
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		routes[pattern] = registered
+	}
+
+	handler := NewCORS(apiPolicy).Handler(mux, routes)
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		headers          map[string]string
+		wantStatus       int
+		wantHeaders      map[string]string
+		wantMissingAllow bool
+	}{
+		{
+			name:   "allowed preflight",
+			method: http.MethodOptions,
+			path:   "/items",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "Authorization",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Headers": "authorization",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:   "preflight resolves the route of the requested method",
+			method: http.MethodOptions,
+			path:   "/items/1",
+			headers: map[string]string{
+				"Origin":                        "https://partner-acme.example.org",
+				"Access-Control-Request-Method": "PUT",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://partner-acme.example.org",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "PUT",
+			},
+		},
+		{
+			name:   "preflight of another route on the same path uses its own policy",
+			method: http.MethodOptions,
+			path:   "/items/1",
+			headers: map[string]string{
+				"Origin":                        "https://partner-acme.example.org",
+				"Access-Control-Request-Method": "POST",
+			},
+			wantStatus:       http.StatusNoContent,
+			wantMissingAllow: true,
+		},
+		{
+			name:   "preflight with a disallowed header",
+			method: http.MethodOptions,
+			path:   "/items",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			wantStatus:       http.StatusNoContent,
+			wantMissingAllow: true,
+		},
+		{
+			name:   "preflight from a disallowed origin",
+			method: http.MethodOptions,
+			path:   "/items",
+			headers: map[string]string{
+				"Origin":                        "https://evil.io",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantStatus:       http.StatusNoContent,
+			wantMissingAllow: true,
+		},
+		{
+			name:   "preflight of an unregistered method is served by the mux",
+			method: http.MethodOptions,
+			path:   "/items",
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			wantStatus:       http.StatusMethodNotAllowed,
+			wantMissingAllow: true,
+		},
+		{
+			name:             "options without preflight headers is served by the mux",
+			method:           http.MethodOptions,
+			path:             "/items",
+			wantStatus:       http.StatusMethodNotAllowed,
+			wantMissingAllow: true,
+		},
+		{
+			name:             "actual requests are served by the mux",
+			method:           http.MethodGet,
+			path:             "/items",
+			headers:          map[string]string{"Origin": "https://app.example.com"},
+			wantStatus:       http.StatusTeapot,
+			wantMissingAllow: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			for key, value := range test.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Errorf("expected status %d, got %d", test.wantStatus, w.Code)
+			}
+
+			for key, want := range test.wantHeaders {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("expected %s=%q, got %q", key, want, got)
+				}
+			}
+
+			if test.wantMissingAllow && w.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Errorf("expected no allowed origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestCORSGuardRoute(t *testing.T) {
+	apiPolicy := newTestCORSPolicy(t, config.CORS{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Request-Id"},
+	})
+	cors := NewCORS(apiPolicy)
+
+	tests := []struct {
+		name       string
+		policy     config.CORS
+		origin     string
+		wantOrigin string
+	}{
+		{"any origin without credentials", config.CORS{}, "https://app.example.com", "*"},
+		{
+			name:       "credentials echo the origin",
+			policy:     newTestCORSPolicy(t, config.CORS{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}),
+			origin:     "https://app.example.com",
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "disallowed origin",
+			policy:     newTestCORSPolicy(t, config.CORS{AllowedOrigins: []string{"https://app.example.com"}}),
+			origin:     "https://evil.io",
+			wantOrigin: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items", nil)
+			r.Header.Set("Origin", test.origin)
+			w := httptest.NewRecorder()
+
+			var hasPolicy bool
+			cors.GuardRoute(w, r, config.Backend{}, config.Route{CORS: test.policy}, func(w http.ResponseWriter, r *http.Request) {
+				hasPolicy = HasCORSPolicy(r.Context())
+			})
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.wantOrigin {
+				t.Errorf("expected allowed origin %q, got %q", test.wantOrigin, got)
+			}
+
+			if !hasPolicy {
+				t.Error("expected the policy to be marked in the request context")
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	var hasPolicy bool
+	NewCORS(config.CORS{}).GuardRoute(w, httptest.NewRequest(http.MethodGet, "/items", nil), config.Backend{}, config.Route{}, func(w http.ResponseWriter, r *http.Request) {
+		hasPolicy = HasCORSPolicy(r.Context())
+	})
+
+	if hasPolicy || w.Header().Get("Vary") != "" {
+		t.Error("expected requests without a policy to be left untouched")
+	}
+}
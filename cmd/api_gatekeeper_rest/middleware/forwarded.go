@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+type Forwarded struct {
+	trustedProxies []netip.Prefix
+}
+
+func NewForwarded(trustedProxies []netip.Prefix) Forwarded {
+	return Forwarded{
+		trustedProxies: trustedProxies,
+	}
+}
+
+// Handle Resolves the request client IP, scheme and host, honouring the forwarding headers of
+// trusted proxies only, and stores them in the request context
+func (m Forwarded) Handle(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	forwarding := httputil.ResolveForwarding(r, m.trustedProxies)
+
+	next(w, r.WithContext(withForwarding(r.Context(), forwarding)))
+}
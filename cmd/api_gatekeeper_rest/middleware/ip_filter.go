@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+type IPFilter struct {
+	apiFilter config.IPFilter
+}
+
+func NewIPFilter(apiFilter config.IPFilter) IPFilter {
+	return IPFilter{
+		apiFilter: apiFilter,
+	}
+}
+
+// GuardRoute Rejects the requests whose client IP is not allowed by the API, backend or route
+// filters. It must run after the Forwarded middleware and before the authentication, so
+// blocked clients can not reach the credentials checks
+func (m IPFilter) GuardRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next http.HandlerFunc,
+) {
+	filters := []config.IPFilter{m.apiFilter, backend.IPFilter(), route.IPFilter()}
+
+	// An unresolved client IP is invalid, so it is rejected by every non empty filter
+	clientIP, _ := netip.ParseAddr(ClientIPFromContext(r.Context()))
+
+	for _, filter := range filters {
+		if filter.IsEmpty() || filter.Allows(clientIP) {
+			continue
+		}
+
+		if route.IsGRPC() {
+			httputil.WriteGRPCError(w, httputil.GRPCCodePermissionDenied, "ip address not allowed")
+			return
+		}
+
+		httputil.WriteIPNotAllowed(w, r)
+		return
+	}
+
+	next(w, r)
+}
@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
-	"github.com/google/uuid"
 	"github.com/gustapinto/api-gatekeeper/internal/config"
 	"github.com/gustapinto/api-gatekeeper/internal/model"
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
 )
 
 type GuardBackendRouteNextFunc = func(http.ResponseWriter, *http.Request, config.Backend, config.Route)
@@ -19,19 +21,56 @@ type AuthService interface {
 	Authorize(model.User, []string) error
 }
 
+type RateLimitService interface {
+	AllowClient(context.Context, config.Backend, config.Route, string, http.Header) (*model.RateLimitStatus, error)
+
+	AllowUser(context.Context, config.Backend, config.Route, model.User, string, http.Header) (*model.RateLimitStatus, error)
+}
+
+type QuotaService interface {
+	Consume(context.Context, config.Backend, config.Route, model.User) (*model.QuotaUsage, error)
+}
+
 type contextKey string
 
 var (
-	userIdContextKey    contextKey = "userId"
-	requestIdContextKey contextKey = "requestId"
+	userIdContextKey     contextKey = "userId"
+	userContextKey       contextKey = "user"
+	forwardingContextKey contextKey = "forwarding"
+	corsContextKey       contextKey = "cors"
 )
 
 func withUserID(parent context.Context, userID string) context.Context {
 	return context.WithValue(parent, userIdContextKey, userID)
 }
 
+func withUser(parent context.Context, user model.User) context.Context {
+	return context.WithValue(parent, userContextKey, user)
+}
+
+// UserFromContext Returns the authenticated user of the request, public routes have no user
+func UserFromContext(ctx context.Context) (model.User, bool) {
+	user, ok := ctx.Value(userContextKey).(model.User)
+	return user, ok
+}
+
 func withRequestID(parent context.Context, requestID string) context.Context {
-	return context.WithValue(parent, requestIdContextKey, requestID)
+	return httputil.WithRequestID(parent, requestID)
+}
+
+// RequestIDFromContext Returns the request ID set by the RequestID middleware
+func RequestIDFromContext(ctx context.Context) string {
+	return httputil.RequestIDFromContext(ctx)
+}
+
+// missingScopes Returns the scopes that failed the authorization, if the error has them
+func missingScopes(err error) []string {
+	var missingScopesErr *service.MissingScopesError
+	if errors.As(err, &missingScopesErr) {
+		return missingScopesErr.Scopes
+	}
+
+	return nil
 }
 
 func mergeScopes(backend config.Backend, route config.Route) []string {
@@ -42,18 +81,28 @@ func mergeScopes(backend config.Backend, route config.Route) []string {
 	return scopes
 }
 
-func getRequestId(r *http.Request) string {
-	requestID := uuid.NewString()
+func withForwarding(parent context.Context, forwarding httputil.Forwarding) context.Context {
+	return context.WithValue(parent, forwardingContextKey, forwarding)
+}
 
-	if r == nil {
-		return requestID
-	}
+// ForwardingFromContext Returns the client information resolved by the Forwarded middleware
+func ForwardingFromContext(ctx context.Context) httputil.Forwarding {
+	forwarding, _ := ctx.Value(forwardingContextKey).(httputil.Forwarding)
+	return forwarding
+}
 
-	if xRequestIdHeader := r.Header.Get("X-RequestId"); len(xRequestIdHeader) > 0 {
-		requestID = xRequestIdHeader
-	} else if xApiGatekeeperRequestIdHeader := r.Header.Get("X-Api-Gatekeeper-RequestId"); len(xApiGatekeeperRequestIdHeader) > 0 {
-		requestID = xApiGatekeeperRequestIdHeader
-	}
+// ClientIPFromContext Returns the client IP resolved by the Forwarded middleware
+func ClientIPFromContext(ctx context.Context) string {
+	return ForwardingFromContext(ctx).ClientIP
+}
+
+func withCORSPolicy(parent context.Context) context.Context {
+	return context.WithValue(parent, corsContextKey, true)
+}
 
-	return requestID
+// HasCORSPolicy Returns if the CORS middleware applied a policy to the request, in which case
+// the backend CORS headers must not reach the client
+func HasCORSPolicy(ctx context.Context) bool {
+	hasPolicy, _ := ctx.Value(corsContextKey).(bool)
+	return hasPolicy
 }
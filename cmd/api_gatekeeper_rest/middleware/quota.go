@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+type Quota struct {
+	quotaService QuotaService
+	logger       *slog.Logger
+}
+
+func NewQuota(quotaService QuotaService, logger *slog.Logger) Quota {
+	return Quota{
+		quotaService: quotaService,
+		logger:       logger,
+	}
+}
+
+// GuardBackendRoute Uses one call of the user quotas, it must run after the rate limits so the
+// requests rejected by them do not consume the quotas. The quotas fail open if the counters
+// can not be reached
+func (m Quota) GuardBackendRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next GuardBackendRouteNextFunc,
+) {
+	if len(backend.Quotas) == 0 && len(route.Quotas) == 0 {
+		next(w, r, backend, route)
+		return
+	}
+
+	user, _ := UserFromContext(r.Context())
+
+	usage, err := m.quotaService.Consume(r.Context(), backend, route, user)
+	if err != nil {
+		m.logger.Error(
+			"Failed to consume quotas, allowing request",
+			"backend", backend.Name,
+			"route", route.Name(),
+			"requestId", RequestIDFromContext(r.Context()),
+			"error", err)
+
+		next(w, r, backend, route)
+		return
+	}
+
+	if usage == nil {
+		next(w, r, backend, route)
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(time.Until(usage.ResetsAt))))
+
+	if route.IsGRPC() {
+		httputil.WriteGRPCError(w, httputil.GRPCCodeResourceExhausted, "quota exceeded")
+		return
+	}
+
+	httputil.WriteTooManyRequests(
+		w,
+		r,
+		"urn:api-gatekeeper:problem:quota-exceeded",
+		fmt.Sprintf("Quota %s of %d calls per %s exceeded, resets at %s", usage.Name, usage.Limit, usage.Period, usage.ResetsAt.Format(time.RFC3339)))
+}
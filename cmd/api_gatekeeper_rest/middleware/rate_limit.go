@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
+)
+
+type RateLimit struct {
+	rateLimitService RateLimitService
+	logger           *slog.Logger
+}
+
+func NewRateLimit(rateLimitService RateLimitService, logger *slog.Logger) RateLimit {
+	return RateLimit{
+		rateLimitService: rateLimitService,
+		logger:           logger,
+	}
+}
+
+// GuardRoute Enforces the backend and route limits keyed by the client IP or a header. It must
+// run before the authentication, so the requests with invalid credentials and the ones to the
+// application routes, like the login, are limited too
+func (m RateLimit) GuardRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next http.HandlerFunc,
+) {
+	if len(backend.RateLimits) == 0 && len(route.RateLimits) == 0 {
+		next(w, r)
+		return
+	}
+
+	status, err := m.rateLimitService.AllowClient(r.Context(), backend, route, ClientIPFromContext(r.Context()), r.Header)
+	if m.reject(w, r, backend, route, status, err) {
+		return
+	}
+
+	next(w, r)
+}
+
+// GuardBackendRoute Enforces the backend and route limits keyed by user, it must run after the
+// authentication so the limits can be keyed by the authenticated user
+func (m RateLimit) GuardBackendRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next GuardBackendRouteNextFunc,
+) {
+	if m.rejectUser(w, r, backend, route) {
+		return
+	}
+
+	next(w, r, backend, route)
+}
+
+// GuardApplicationRoute Is the GuardBackendRoute of the application routes
+func (m RateLimit) GuardApplicationRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	next GuardApplicationRouteNextFunc,
+) {
+	if m.rejectUser(w, r, backend, route) {
+		return
+	}
+
+	next(w, r)
+}
+
+func (m RateLimit) rejectUser(w http.ResponseWriter, r *http.Request, backend config.Backend, route config.Route) bool {
+	if len(backend.RateLimits) == 0 && len(route.RateLimits) == 0 {
+		return false
+	}
+
+	user, _ := UserFromContext(r.Context())
+
+	status, err := m.rateLimitService.AllowUser(r.Context(), backend, route, user, ClientIPFromContext(r.Context()), r.Header)
+
+	return m.reject(w, r, backend, route, status, err)
+}
+
+// reject Writes the rate limit headers of the status and, if the request exceeded a limit, the
+// rate limited error. The limits fail open if the counters can not be reached
+func (m RateLimit) reject(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend config.Backend,
+	route config.Route,
+	status *model.RateLimitStatus,
+	err error,
+) bool {
+	if err != nil {
+		m.logger.Error(
+			"Failed to check rate limits, allowing request",
+			"backend", backend.Name,
+			"route", route.Name(),
+			"requestId", RequestIDFromContext(r.Context()),
+			"error", err)
+
+		return false
+	}
+
+	if status == nil {
+		return false
+	}
+
+	// The headers of an earlier check are kept if they have fewer remaining requests
+	if remaining, err := strconv.Atoi(w.Header().Get("RateLimit-Remaining")); status.Allowed && err == nil && remaining <= status.Remaining {
+		return false
+	}
+
+	resetSeconds := strconv.Itoa(ceilSeconds(status.Reset))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("RateLimit-Reset", resetSeconds)
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", status.Limit, ceilSeconds(status.Window)))
+
+	if status.Allowed {
+		return false
+	}
+
+	w.Header().Set("Retry-After", resetSeconds)
+
+	if route.IsGRPC() {
+		httputil.WriteGRPCError(w, httputil.GRPCCodeResourceExhausted, "rate limit exceeded")
+		return true
+	}
+
+	httputil.WriteTooManyRequests(
+		w,
+		r,
+		"urn:api-gatekeeper:problem:rate-limited",
+		fmt.Sprintf("Rate limit %s exceeded, retry after %s seconds", status.Name, resetSeconds))
+
+	return true
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+	"github.com/gustapinto/api-gatekeeper/internal/repository/memory"
+	"github.com/gustapinto/api-gatekeeper/internal/service"
+)
+
+type rejectingAuthService struct{}
+
+func (rejectingAuthService) AuthenticateToken(string) (model.User, error) {
+	return model.User{}, errors.New("invalid credentials")
+}
+
+func (rejectingAuthService) Authorize(model.User, []string) error {
+	return nil
+}
+
+func newTestRateLimits(limits ...config.RateLimit) config.RateLimits {
+	rateLimits := config.RateLimits(limits)
+	rateLimits.Normalize()
+
+	return rateLimits
+}
+
+// serveRateLimitedRoute Runs the route through the same middleware order used by the server
+func serveRateLimitedRoute(rateLimit RateLimit, backend config.Backend, route config.Route, handler http.HandlerFunc) int {
+	r := httptest.NewRequest(http.MethodPost, route.GatekeeperPath, nil)
+	r.RemoteAddr = "203.0.113.7:41000"
+	w := httptest.NewRecorder()
+	auth := NewAuth(rejectingAuthService{})
+
+	NewForwarded(nil).Handle(w, r, func(w http.ResponseWriter, r *http.Request) {
+		rateLimit.GuardRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+			auth.GuardApplicationRoute(w, r, backend, route, func(w http.ResponseWriter, r *http.Request) {
+				rateLimit.GuardApplicationRoute(w, r, backend, route, handler)
+			})
+		})
+	})
+
+	return w.Code
+}
+
+func TestRateLimitCountsRequestsRejectedByTheAuthentication(t *testing.T) {
+	rateLimit := NewRateLimit(service.NewRateLimiter(memory.NewLimiter()), slog.New(slog.DiscardHandler))
+	backend := config.Backend{
+		Name:       "api-gatekeeper",
+		RateLimits: newTestRateLimits(config.RateLimit{KeyBy: config.RateLimitKeyIP, Requests: 2, WindowSeconds: 60}),
+	}
+	route := config.Route{Method: http.MethodPost, GatekeeperPath: "/api-gatekeeper/v1/users"}
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if got := serveRateLimitedRoute(rateLimit, backend, route, func(http.ResponseWriter, *http.Request) {}); got != want {
+			t.Fatalf("request %d: expected status %d, got %d", i, want, got)
+		}
+	}
+}
+
+func TestRateLimitGuardsPublicApplicationRoutes(t *testing.T) {
+	rateLimit := NewRateLimit(service.NewRateLimiter(memory.NewLimiter()), slog.New(slog.DiscardHandler))
+	backend := config.Backend{
+		Name:       "api-gatekeeper",
+		RateLimits: newTestRateLimits(config.RateLimit{KeyBy: config.RateLimitKeyIP, Requests: 1, WindowSeconds: 60}),
+	}
+	route := config.Route{Method: http.MethodPost, GatekeeperPath: "/api-gatekeeper/v1/users/login", IsPublic: true}
+
+	// A failed login is answered by the handler, after every limit
+	login := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if got := serveRateLimitedRoute(rateLimit, backend, route, login); got != want {
+			t.Fatalf("request %d: expected status %d, got %d", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength Limits the size of client provided request IDs, as they are logged and
+// forwarded to the backends
+const maxRequestIDLength = 128
+
+// legacyRequestIDHeaders Are still read when the configured header is missing, so clients of the
+// previous gatekeeper versions keep their request IDs
+var legacyRequestIDHeaders = []string{"X-RequestId", "X-Api-Gatekeeper-RequestId"}
+
+type RequestID struct {
+	header string
+}
+
+func NewRequestID(header string) RequestID {
+	return RequestID{
+		header: header,
+	}
+}
+
+// Handle Reads the request ID from the configured header, falling back to the legacy
+// X-RequestId and X-Api-Gatekeeper-RequestId headers and generating a new one if all of them are
+// missing or invalid, stores it in the request context and echoes it in the response headers
+func (m RequestID) Handle(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID := m.fromHeaders(r.Header)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	w.Header().Set(m.header, requestID)
+
+	next(w, r.WithContext(withRequestID(r.Context(), requestID)))
+}
+
+func (m RequestID) fromHeaders(header http.Header) string {
+	for _, key := range append([]string{m.header}, legacyRequestIDHeaders...) {
+		if requestID := header.Get(key); isValidRequestID(requestID) {
+			return requestID
+		}
+	}
+
+	return ""
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
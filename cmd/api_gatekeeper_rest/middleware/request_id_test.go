@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"configured header", map[string]string{"X-Request-Id": "abc", "X-RequestId": "legacy"}, "abc"},
+		{"legacy header", map[string]string{"X-RequestId": "legacy"}, "legacy"},
+		{"legacy gatekeeper header", map[string]string{"X-Api-Gatekeeper-RequestId": "gatekeeper"}, "gatekeeper"},
+		{"invalid configured header", map[string]string{"X-Request-Id": "has space", "X-RequestId": "legacy"}, "legacy"},
+		{"too long", map[string]string{"X-Request-Id": strings.Repeat("a", maxRequestIDLength+1)}, ""},
+		{"missing", map[string]string{}, ""},
+	}
+
+	requestID := NewRequestID("X-Request-Id")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range test.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			var got string
+			requestID.Handle(w, r, func(w http.ResponseWriter, r *http.Request) {
+				got = RequestIDFromContext(r.Context())
+			})
+
+			if test.want != "" && got != test.want {
+				t.Errorf("expected request ID %q, got %q", test.want, got)
+			}
+
+			if !isValidRequestID(got) {
+				t.Errorf("expected a valid request ID, got %q", got)
+			}
+
+			if echoed := w.Header().Get("X-Request-Id"); echoed != got {
+				t.Errorf("expected the request ID %q to be echoed, got %q", got, echoed)
+			}
+		})
+	}
+}
@@ -1,8 +1,6 @@
 module github.com/gustapinto/api-gatekeeper
 
-go 1.23.0
-
-toolchain go1.24.0
+go 1.24.0
 
 require gopkg.in/yaml.v3 v3.0.1
 
@@ -2,10 +2,29 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 )
 
+const defaultRequestIDHeader = "X-Request-Id"
+
+type ErrorFormat string
+
+const (
+	ErrorFormatProblem ErrorFormat = "problem"
+	ErrorFormatLegacy  ErrorFormat = "legacy"
+)
+
+type LimiterStore string
+
+const (
+	LimiterStoreMemory   LimiterStore = "memory"
+	LimiterStoreDatabase LimiterStore = "database"
+)
+
 type AuthType string
 
 const (
@@ -14,11 +33,23 @@ const (
 )
 
 type API struct {
-	Address         string   `yaml:"address"`
-	TokenExpiration string   `yaml:"tokenExpiration"`
-	JwtSecret       string   `yaml:"jwtSecret"`
-	AuthType        AuthType `yaml:"authType"`
-	User            User     `yaml:"user"`
+	Address               string            `yaml:"address"`
+	TokenExpiration       string            `yaml:"tokenExpiration"`
+	JwtSecret             string            `yaml:"jwtSecret"`
+	AuthType              AuthType          `yaml:"authType"`
+	H2C                   bool              `yaml:"h2c"`
+	RequestIDHeader       string            `yaml:"requestIdHeader"`
+	TrustedProxies        []string          `yaml:"trustedProxies"`
+	IPAllow               []string          `yaml:"ipAllow"`
+	IPDeny                []string          `yaml:"ipDeny"`
+	ManagementIPAllow     []string          `yaml:"managementIpAllow"`
+	ApplicationRateLimits RateLimits        `yaml:"applicationRateLimits"`
+	CORS                  CORS              `yaml:"cors"`
+	ErrorFormat           ErrorFormat       `yaml:"errorFormat"`
+	LimiterStore          LimiterStore      `yaml:"limiterStore"`
+	IdentityAssertion     IdentityAssertion `yaml:"identityAssertion"`
+	User                  User              `yaml:"user"`
+	ipFilter              IPFilter
 }
 
 func (a API) Validate() error {
@@ -38,9 +69,94 @@ func (a API) Validate() error {
 		return err
 	}
 
+	if a.ErrorFormat != "" && a.ErrorFormat != ErrorFormatProblem && a.ErrorFormat != ErrorFormatLegacy {
+		return errors.New("config 'api.errorFormat' must be one of [problem, legacy]")
+	}
+
+	if a.LimiterStore != "" && a.LimiterStore != LimiterStoreMemory && a.LimiterStore != LimiterStoreDatabase {
+		return errors.New("config 'api.limiterStore' must be one of [memory, database]")
+	}
+
+	for _, trustedProxy := range a.TrustedProxies {
+		if _, err := parsePrefix(trustedProxy); err != nil {
+			return fmt.Errorf("config 'api.trustedProxies' must only contain IP addresses or CIDR ranges, got %s", trustedProxy)
+		}
+	}
+
+	if err := validateIPFilter("api", a.IPAllow, a.IPDeny); err != nil {
+		return err
+	}
+
+	if err := validateIPFilter("api.management", a.ManagementIPAllow, nil); err != nil {
+		return err
+	}
+
+	if err := a.ApplicationRateLimits.Validate(); err != nil {
+		return err
+	}
+
+	if err := a.CORS.Validate(); err != nil {
+		return err
+	}
+
+	if err := a.IdentityAssertion.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func (a *API) Normalize() {
+	if a.ErrorFormat == "" {
+		a.ErrorFormat = ErrorFormatProblem
+	}
+
+	if a.LimiterStore == "" {
+		a.LimiterStore = LimiterStoreMemory
+	}
+
+	if strings.TrimSpace(a.RequestIDHeader) == "" {
+		a.RequestIDHeader = defaultRequestIDHeader
+	}
+
+	a.RequestIDHeader = http.CanonicalHeaderKey(a.RequestIDHeader)
+	a.IdentityAssertion.Normalize()
+	a.ApplicationRateLimits.Normalize()
+	a.CORS.Normalize()
+	a.ipFilter = newIPFilter(a.IPAllow, a.IPDeny)
+}
+
+// IPFilter Returns the filter applied to every route
+func (a API) IPFilter() IPFilter {
+	return a.ipFilter
+}
+
+// TrustedProxyPrefixes Returns the trusted proxies as CIDR ranges, single addresses are
+// converted to single address ranges
+func (a API) TrustedProxyPrefixes() []netip.Prefix {
+	return parsePrefixes(a.TrustedProxies)
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return prefix.Masked(), nil
+}
+
 func (a API) TokenDuration() time.Duration {
 	duration, err := time.ParseDuration(a.TokenExpiration)
 	if err != nil {
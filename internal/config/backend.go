@@ -7,12 +7,34 @@ import (
 )
 
 type Backend struct {
-	Name        string            `yaml:"name"`
-	Host        string            `yaml:"host"`
-	PassHeaders bool              `yaml:"passHeaders"`
-	Scopes      []string          `yaml:"scopes"`
-	Headers     map[string]string `yaml:"headers"`
-	Routes      []Route           `yaml:"routes"`
+	Name                string            `yaml:"name"`
+	Host                string            `yaml:"host"`
+	Targets             []Target          `yaml:"targets"`
+	LoadBalancing       LoadBalancing     `yaml:"loadBalancing"`
+	PassHeaders         bool              `yaml:"passHeaders"`
+	Scopes              []string          `yaml:"scopes"`
+	Headers             map[string]string `yaml:"headers"`
+	ResponseHeaders     ResponseHeaders   `yaml:"responseHeaders"`
+	RequestHeaderRules  HeaderRules       `yaml:"requestHeaderRules"`
+	ResponseHeaderRules HeaderRules       `yaml:"responseHeaderRules"`
+	Transport           Transport         `yaml:"transport"`
+	HealthCheck         HealthCheck       `yaml:"healthCheck"`
+	CircuitBreaker      CircuitBreaker    `yaml:"circuitBreaker"`
+	OutlierDetection    OutlierDetection  `yaml:"outlierDetection"`
+	Retry               Retry             `yaml:"retry"`
+	Protocol            BackendProtocol   `yaml:"protocol"`
+	MountPath           string            `yaml:"mountPath"`
+	StripPrefix         bool              `yaml:"stripPrefix"`
+	ReplacePrefix       string            `yaml:"replacePrefix"`
+	MountTimeoutSeconds int               `yaml:"mountTimeoutSeconds"`
+	ErrorBodies         map[int]ErrorBody `yaml:"errorBodies"`
+	RateLimits          RateLimits        `yaml:"rateLimits"`
+	Quotas              []string          `yaml:"quotas"`
+	IPAllow             []string          `yaml:"ipAllow"`
+	IPDeny              []string          `yaml:"ipDeny"`
+	CORS                CORS              `yaml:"cors"`
+	Routes              []Route           `yaml:"routes"`
+	ipFilter            IPFilter
 }
 
 func (b Backend) Validate() error {
@@ -20,8 +42,80 @@ func (b Backend) Validate() error {
 		return errors.New("config 'backend.name' must be present and not be empty")
 	}
 
-	if strings.TrimSpace(b.Host) == "" {
-		return errors.New("config 'backend.host' must be present and not be empty")
+	if len(b.Routes) == 0 && !b.IsMounted() {
+		return errors.New("config 'backend.routes' or 'backend.mountPath' must be present and not be empty")
+	}
+
+	if strings.TrimSpace(b.Host) == "" && len(b.Targets) == 0 {
+		return errors.New("config 'backend.host' or 'backend.targets' must be present and not be empty")
+	}
+
+	for _, target := range b.Targets {
+		if err := target.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := b.LoadBalancing.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.ResponseHeaders.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.RequestHeaderRules.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.ResponseHeaderRules.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.HealthCheck.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.CircuitBreaker.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.OutlierDetection.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.Protocol.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.validateMount(); err != nil {
+		return err
+	}
+
+	for status, errorBody := range b.ErrorBodies {
+		if err := errorBody.Validate(status); err != nil {
+			return err
+		}
+	}
+
+	if err := b.RateLimits.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.CORS.Validate(); err != nil {
+		return err
+	}
+
+	if err := validateIPFilter("backend", b.IPAllow, b.IPDeny); err != nil {
+		return err
 	}
 
 	return nil
@@ -35,6 +129,43 @@ func (b *Backend) Normalize() {
 	if b.Headers == nil {
 		b.Headers = make(map[string]string)
 	}
+
+	if len(b.Targets) == 0 && strings.TrimSpace(b.Host) != "" {
+		b.Targets = []Target{{Host: b.Host}}
+	}
+
+	for i := range b.Targets {
+		b.Targets[i].Normalize()
+	}
+
+	b.LoadBalancing.Normalize()
+	b.ResponseHeaders.Normalize()
+	b.RequestHeaderRules.Normalize()
+	b.ResponseHeaderRules.Normalize()
+	b.Transport.Normalize()
+	b.HealthCheck.Normalize()
+	b.CircuitBreaker.Normalize()
+	b.OutlierDetection.Normalize()
+	b.Retry.Normalize()
+	b.RateLimits.Normalize()
+	b.CORS.Normalize()
+	b.ipFilter = newIPFilter(b.IPAllow, b.IPDeny)
+
+	if b.Protocol == "" {
+		b.Protocol = BackendProtocolAuto
+	}
+
+	b.normalizeMount()
+
+	for status, errorBody := range b.ErrorBodies {
+		errorBody.Normalize(status)
+		b.ErrorBodies[status] = errorBody
+	}
+}
+
+// IPFilter Returns the filter shared by every route of this backend
+func (b Backend) IPFilter() IPFilter {
+	return b.ipFilter
 }
 
 func (b *Backend) ValidateAndNormalize() error {
@@ -44,12 +175,16 @@ func (b *Backend) ValidateAndNormalize() error {
 
 	b.Normalize()
 
-	for _, route := range b.Routes {
-		if err := route.ValidateAndNormalize(); err != nil {
+	for i := range b.Routes {
+		if err := b.Routes[i].ValidateAndNormalize(); err != nil {
 			return err
 		}
 	}
 
+	if b.IsMounted() {
+		b.Routes = append(b.Routes, b.MountRoute())
+	}
+
 	return nil
 }
 
@@ -67,14 +202,38 @@ type apiGatekeeperUserHandler interface {
 	Login(http.ResponseWriter, *http.Request)
 }
 
-func (Backend) APIGatekeeperBackend(userHandler apiGatekeeperUserHandler) Backend {
-	return Backend{
+type apiGatekeeperQuotaHandler interface {
+	GetByUser(http.ResponseWriter, *http.Request)
+
+	Reset(http.ResponseWriter, *http.Request)
+
+	ResetAll(http.ResponseWriter, *http.Request)
+}
+
+type apiGatekeeperBackendHandler interface {
+	GetHealth(http.ResponseWriter, *http.Request)
+
+	GetJWKS(http.ResponseWriter, *http.Request)
+}
+
+// APIGatekeeperBackend Returns the backend of the reserved /api-gatekeeper/ routes, the non public
+// ones are only reachable from the managementIPAllow addresses, if present. The rateLimits apply
+// to every one of the routes
+func (Backend) APIGatekeeperBackend(
+	userHandler apiGatekeeperUserHandler,
+	quotaHandler apiGatekeeperQuotaHandler,
+	backendHandler apiGatekeeperBackendHandler,
+	managementIPAllow []string,
+	rateLimits RateLimits,
+) Backend {
+	backend := Backend{
 		Name: "api-gatekeeper",
 		Host: "",
 		Scopes: []string{
 			"api-gatekeeper.manage-users",
 		},
-		Headers: nil,
+		Headers:    nil,
+		RateLimits: rateLimits,
 		Routes: []Route{
 			{
 				Method:         "POST",
@@ -101,12 +260,47 @@ func (Backend) APIGatekeeperBackend(userHandler apiGatekeeperUserHandler) Backen
 				GatekeeperPath: "/api-gatekeeper/v1/users/{userId}",
 				HandlerFunc:    userHandler.GetByID,
 			},
+			{
+				Method:         "GET",
+				GatekeeperPath: "/api-gatekeeper/v1/users/{userId}/quotas",
+				HandlerFunc:    quotaHandler.GetByUser,
+			},
+			{
+				Method:         "DELETE",
+				GatekeeperPath: "/api-gatekeeper/v1/users/{userId}/quotas",
+				HandlerFunc:    quotaHandler.ResetAll,
+			},
+			{
+				Method:         "DELETE",
+				GatekeeperPath: "/api-gatekeeper/v1/users/{userId}/quotas/{quotaName}",
+				HandlerFunc:    quotaHandler.Reset,
+			},
 			{
 				Method:         "POST",
 				GatekeeperPath: "/api-gatekeeper/v1/users/login",
 				HandlerFunc:    userHandler.Login,
 				IsPublic:       true,
 			},
+			{
+				Method:         "GET",
+				GatekeeperPath: "/api-gatekeeper/v1/backends/health",
+				HandlerFunc:    backendHandler.GetHealth,
+			},
+			{
+				Method:         "GET",
+				GatekeeperPath: "/api-gatekeeper/v1/.well-known/jwks.json",
+				HandlerFunc:    backendHandler.GetJWKS,
+				IsPublic:       true,
+			},
 		},
 	}
+
+	managementIPFilter := newIPFilter(managementIPAllow, nil)
+	for i := range backend.Routes {
+		if !backend.Routes[i].IsPublic {
+			backend.Routes[i].ipFilter = managementIPFilter
+		}
+	}
+
+	return backend
 }
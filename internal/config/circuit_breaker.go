@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultCircuitBreakerMinimumRequests = 10
+	defaultCircuitBreakerWindowSeconds   = 60
+	defaultCircuitBreakerOpenSeconds     = 30
+	defaultCircuitBreakerHalfOpenProbes  = 1
+)
+
+type CircuitBreaker struct {
+	ConsecutiveFailures          int     `yaml:"consecutiveFailures"`
+	FailureRate                  float64 `yaml:"failureRate"`
+	SlowCallDurationMilliseconds int     `yaml:"slowCallDurationMilliseconds"`
+	SlowCallRate                 float64 `yaml:"slowCallRate"`
+	MinimumRequests              int     `yaml:"minimumRequests"`
+	WindowSeconds                int     `yaml:"windowSeconds"`
+	OpenSeconds                  int     `yaml:"openSeconds"`
+	HalfOpenProbes               int     `yaml:"halfOpenProbes"`
+}
+
+func (c CircuitBreaker) IsEnabled() bool {
+	return c.ConsecutiveFailures > 0 || c.FailureRate > 0 || (c.SlowCallDurationMilliseconds > 0 && c.SlowCallRate > 0)
+}
+
+func (c CircuitBreaker) Validate() error {
+	if c.ConsecutiveFailures < 0 {
+		return errors.New("config 'circuitBreaker.consecutiveFailures' must not be negative")
+	}
+
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return errors.New("config 'circuitBreaker.failureRate' must be between 0 and 1")
+	}
+
+	if c.SlowCallRate < 0 || c.SlowCallRate > 1 {
+		return errors.New("config 'circuitBreaker.slowCallRate' must be between 0 and 1")
+	}
+
+	if c.SlowCallDurationMilliseconds < 0 ||
+		c.MinimumRequests < 0 ||
+		c.WindowSeconds < 0 ||
+		c.OpenSeconds < 0 ||
+		c.HalfOpenProbes < 0 {
+		return errors.New("config 'circuitBreaker' durations and counts must not be negative")
+	}
+
+	return nil
+}
+
+func (c *CircuitBreaker) Normalize() {
+	if !c.IsEnabled() {
+		return
+	}
+
+	if c.MinimumRequests == 0 {
+		c.MinimumRequests = defaultCircuitBreakerMinimumRequests
+	}
+
+	if c.WindowSeconds == 0 {
+		c.WindowSeconds = defaultCircuitBreakerWindowSeconds
+	}
+
+	if c.OpenSeconds == 0 {
+		c.OpenSeconds = defaultCircuitBreakerOpenSeconds
+	}
+
+	if c.HalfOpenProbes == 0 {
+		c.HalfOpenProbes = defaultCircuitBreakerHalfOpenProbes
+	}
+}
+
+func (c CircuitBreaker) SlowCallDuration() time.Duration {
+	return time.Duration(c.SlowCallDurationMilliseconds) * time.Millisecond
+}
+
+func (c CircuitBreaker) Window() time.Duration {
+	return time.Duration(c.WindowSeconds) * time.Second
+}
+
+func (c CircuitBreaker) OpenDuration() time.Duration {
+	return time.Duration(c.OpenSeconds) * time.Second
+}
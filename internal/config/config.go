@@ -12,28 +12,39 @@ import (
 type Config struct {
 	API      API       `yaml:"api"`
 	Database Database  `yaml:"database"`
+	Quotas   Quotas    `yaml:"quotas"`
 	Backends []Backend `yaml:"backends"`
 }
 
-func (c Config) ValidateAndNormalize() error {
+func (c *Config) ValidateAndNormalize() error {
 	if err := c.API.Validate(); err != nil {
 		return err
 	}
 
+	c.API.Normalize()
+
 	if err := c.Database.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.Quotas.Validate(); err != nil {
+		return err
+	}
+
 	if len(c.Backends) == 0 {
 		return errors.New("config 'backends' must be present and not be empty")
 	}
 
-	for _, backend := range c.Backends {
-		if err := backend.ValidateAndNormalize(); err != nil {
+	for i := range c.Backends {
+		if err := c.Backends[i].ValidateAndNormalize(); err != nil {
 			return err
 		}
 	}
 
+	if err := c.Quotas.validateReferences(c.Backends); err != nil {
+		return err
+	}
+
 	return nil
 }
 
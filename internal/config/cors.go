@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// corsRegexPrefix Marks the allowed origins that are regular expressions
+const corsRegexPrefix = "regex:"
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORS Is a cross-origin resource sharing policy, it is enabled when it has allowed origins.
+// The origins can be exact ("https://app.example.com"), contain * wildcards
+// ("https://*.example.com") or be regular expressions prefixed by "regex:"
+type CORS struct {
+	AllowedOrigins   []string `yaml:"allowedOrigins"`
+	AllowedMethods   []string `yaml:"allowedMethods"`
+	AllowedHeaders   []string `yaml:"allowedHeaders"`
+	ExposedHeaders   []string `yaml:"exposedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials"`
+	MaxAgeSeconds    int      `yaml:"maxAgeSeconds"`
+	anyOrigin        bool
+	originPatterns   []*regexp.Regexp
+}
+
+func (c CORS) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if strings.TrimSpace(origin) == "" {
+			return errors.New("config 'cors.allowedOrigins' must not contain empty origins")
+		}
+
+		if _, err := compileCORSOrigin(origin); err != nil {
+			return fmt.Errorf("config 'cors.allowedOrigins' must only contain valid origins or regular expressions, got %s", origin)
+		}
+	}
+
+	// Echoing any origin with credentials would let every site make credentialed calls
+	if c.AllowCredentials && slices.ContainsFunc(c.AllowedOrigins, func(origin string) bool {
+		return strings.TrimSpace(origin) == "*"
+	}) {
+		return errors.New("config 'cors.allowedOrigins' must not contain * when 'cors.allowCredentials' is true, list the origins or use a regular expression")
+	}
+
+	for _, method := range c.AllowedMethods {
+		if strings.TrimSpace(method) == "" {
+			return errors.New("config 'cors.allowedMethods' must not contain empty methods")
+		}
+	}
+
+	for _, header := range append(append([]string{}, c.AllowedHeaders...), c.ExposedHeaders...) {
+		if strings.TrimSpace(header) == "" {
+			return errors.New("config 'cors.allowedHeaders' and 'cors.exposedHeaders' must not contain empty header names")
+		}
+	}
+
+	if c.MaxAgeSeconds < 0 {
+		return errors.New("config 'cors.maxAgeSeconds' must not be negative")
+	}
+
+	return nil
+}
+
+func (c *CORS) Normalize() {
+	if !c.IsEnabled() {
+		return
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = slices.Clone(defaultCORSMethods)
+	}
+
+	for i := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(strings.TrimSpace(c.AllowedMethods[i]))
+	}
+
+	for i := range c.AllowedHeaders {
+		if header := strings.TrimSpace(c.AllowedHeaders[i]); header != "*" {
+			c.AllowedHeaders[i] = http.CanonicalHeaderKey(header)
+		}
+	}
+
+	for i := range c.ExposedHeaders {
+		c.ExposedHeaders[i] = http.CanonicalHeaderKey(strings.TrimSpace(c.ExposedHeaders[i]))
+	}
+
+	c.anyOrigin = false
+	c.originPatterns = make([]*regexp.Regexp, 0, len(c.AllowedOrigins))
+	for _, origin := range c.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			c.anyOrigin = true
+			continue
+		}
+
+		if pattern, err := compileCORSOrigin(origin); err == nil {
+			c.originPatterns = append(c.originPatterns, pattern)
+		}
+	}
+}
+
+// IsEnabled Returns if the policy has allowed origins
+func (c CORS) IsEnabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// AllowsAnyOrigin Returns if the policy allows every origin with the * wildcard
+func (c CORS) AllowsAnyOrigin() bool {
+	return c.anyOrigin
+}
+
+// AllowsOrigin Checks the request Origin header against the allowed origins
+func (c CORS) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	if c.anyOrigin {
+		return true
+	}
+
+	for _, pattern := range c.originPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsMethod Checks the preflight requested method against the allowed methods
+func (c CORS) AllowsMethod(method string) bool {
+	return slices.Contains(c.AllowedMethods, strings.ToUpper(method))
+}
+
+// AllowsAnyHeader Returns if the policy allows every request header with the * wildcard
+func (c CORS) AllowsAnyHeader() bool {
+	return slices.Contains(c.AllowedHeaders, "*")
+}
+
+// AllowsHeader Checks a preflight requested header against the allowed headers
+func (c CORS) AllowsHeader(header string) bool {
+	return c.AllowsAnyHeader() || slices.Contains(c.AllowedHeaders, http.CanonicalHeaderKey(header))
+}
+
+// compileCORSOrigin Compiles an allowed origin to an expression matching the whole origin, the
+// exact and wildcard origins are matched case insensitively
+func compileCORSOrigin(origin string) (*regexp.Regexp, error) {
+	origin = strings.TrimSpace(origin)
+
+	if expression, isRegex := strings.CutPrefix(origin, corsRegexPrefix); isRegex {
+		return regexp.Compile("^(?:" + expression + ")$")
+	}
+
+	if origin == "*" {
+		return regexp.Compile(".*")
+	}
+
+	expression := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[^/]+`)
+
+	return regexp.Compile("(?i)^" + expression + "$")
+}
@@ -0,0 +1,101 @@
+package config
+
+import "testing"
+
+func newTestCORS(cors CORS) CORS {
+	cors.Normalize()
+	return cors
+}
+
+func TestCORSAllowsOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"exact", []string{"https://app.example.com"}, "https://app.example.com", true},
+		{"exact is case insensitive", []string{"https://App.Example.com"}, "https://app.example.com", true},
+		{"exact does not match other scheme", []string{"https://app.example.com"}, "http://app.example.com", false},
+		{"exact does not match suffix", []string{"https://app.example.com"}, "https://app.example.com.evil.io", false},
+		{"exact does not match other port", []string{"https://app.example.com"}, "https://app.example.com:8443", false},
+		{"wildcard subdomain", []string{"https://*.example.com"}, "https://app.example.com", true},
+		{"wildcard nested subdomain", []string{"https://*.example.com"}, "https://a.b.example.com", true},
+		{"wildcard requires a subdomain", []string{"https://*.example.com"}, "https://example.com", false},
+		{"wildcard escapes dots", []string{"https://*.example.com"}, "https://app.exampleXcom", false},
+		{"wildcard does not match other domain", []string{"https://*.example.com"}, "https://example.com.evil.io", false},
+		{"wildcard port", []string{"http://localhost:*"}, "http://localhost:3000", true},
+		{"regex", []string{`regex:^https://app-[0-9]+\.example\.com$`}, "https://app-42.example.com", true},
+		{"regex is anchored", []string{`regex:https://app-[0-9]+\.example\.com`}, "https://app-42.example.com.evil.io", false},
+		{"regex alternatives are anchored", []string{`regex:https://a\.example\.com|https://b\.example\.com`}, "https://evil.io/https://b.example.com", false},
+		{"any origin", []string{"*"}, "https://whatever.io", true},
+		{"empty origin", []string{"*"}, "", false},
+		{"second origin", []string{"https://a.example.com", "https://b.example.com"}, "https://b.example.com", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cors := newTestCORS(CORS{AllowedOrigins: test.allowed})
+			if got := cors.AllowsOrigin(test.origin); got != test.want {
+				t.Errorf("expected %t for %q, got %t", test.want, test.origin, got)
+			}
+		})
+	}
+}
+
+func TestCORSValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    CORS
+		wantErr bool
+	}{
+		{"disabled", CORS{}, false},
+		{"valid", CORS{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true, MaxAgeSeconds: 60}, false},
+		{"any origin without credentials", CORS{AllowedOrigins: []string{"*"}}, false},
+		{"any origin with credentials", CORS{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}, true},
+		{"empty origin", CORS{AllowedOrigins: []string{" "}}, true},
+		{"invalid regex", CORS{AllowedOrigins: []string{"regex:(["}}, true},
+		{"empty method", CORS{AllowedOrigins: []string{"*"}, AllowedMethods: []string{""}}, true},
+		{"empty header", CORS{AllowedOrigins: []string{"*"}, ExposedHeaders: []string{""}}, true},
+		{"negative max age", CORS{AllowedOrigins: []string{"*"}, MaxAgeSeconds: -1}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.cors.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("expected error=%t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCORSNormalize(t *testing.T) {
+	cors := newTestCORS(CORS{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"authorization", " content-type "},
+	})
+
+	for _, method := range []string{"GET", "post", "DELETE"} {
+		if !cors.AllowsMethod(method) {
+			t.Errorf("expected the default methods to allow %s", method)
+		}
+	}
+
+	if cors.AllowsMethod("TRACE") {
+		t.Error("expected the default methods to not allow TRACE")
+	}
+
+	if !cors.AllowsHeader("Authorization") || !cors.AllowsHeader("content-type") || cors.AllowsHeader("X-Custom") {
+		t.Errorf("unexpected allowed headers %v", cors.AllowedHeaders)
+	}
+
+	anyHeader := newTestCORS(CORS{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	if !anyHeader.AllowsHeader("X-Custom") {
+		t.Error("expected the * header to allow any header")
+	}
+
+	disabled := newTestCORS(CORS{})
+	if disabled.IsEnabled() || len(disabled.AllowedMethods) != 0 {
+		t.Errorf("expected a policy without origins to stay disabled, got %+v", disabled)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+const defaultErrorBodyContentType = "application/json"
+
+// ErrorBodyTemplateData Is the data available to the custom error bodies templates
+type ErrorBodyTemplateData struct {
+	Status    int
+	Message   string
+	RequestID string
+}
+
+// errorBodyTemplate Is implemented by both the text and html templates
+type errorBodyTemplate interface {
+	Execute(io.Writer, any) error
+}
+
+// ErrorBody Is a custom body sent to clients instead of the default gatekeeper error response.
+// The request ID may come from the client, so the templates are escaped by the content type:
+// HTML bodies use html/template and JSON bodies receive the strings already escaped to be placed
+// between quotes
+type ErrorBody struct {
+	ContentType string `yaml:"contentType"`
+	Body        string `yaml:"body"`
+
+	bodyTemplate errorBodyTemplate
+}
+
+func (e ErrorBody) Validate(status int) error {
+	if status < http.StatusBadRequest || status > 599 {
+		return fmt.Errorf("config 'errorBodies' status %d must be an error status, between 400 and 599", status)
+	}
+
+	bodyTemplate, err := parseErrorBodyTemplate(status, e.contentType(), e.Body)
+	if err != nil {
+		return fmt.Errorf("config 'errorBodies.body' of status %d must be a valid template: %w", status, err)
+	}
+
+	if err := bodyTemplate.Execute(io.Discard, ErrorBodyTemplateData{}); err != nil {
+		return fmt.Errorf("config 'errorBodies.body' of status %d must be a valid template: %w", status, err)
+	}
+
+	return nil
+}
+
+func (e *ErrorBody) Normalize(status int) {
+	e.ContentType = e.contentType()
+
+	bodyTemplate, err := parseErrorBodyTemplate(status, e.ContentType, e.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	e.bodyTemplate = bodyTemplate
+}
+
+func (e ErrorBody) Render(data ErrorBodyTemplateData) ([]byte, error) {
+	if e.bodyTemplate == nil {
+		return []byte(e.Body), nil
+	}
+
+	if isJSONContentType(e.ContentType) {
+		data.Message = jsonStringContent(data.Message)
+		data.RequestID = jsonStringContent(data.RequestID)
+	}
+
+	var body bytes.Buffer
+	if err := e.bodyTemplate.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+func (e ErrorBody) contentType() string {
+	if strings.TrimSpace(e.ContentType) == "" {
+		return defaultErrorBodyContentType
+	}
+
+	return e.ContentType
+}
+
+func parseErrorBodyTemplate(status int, contentType, body string) (errorBodyTemplate, error) {
+	name := fmt.Sprint(status)
+
+	if isHTMLContentType(contentType) {
+		return htmltemplate.New(name).Parse(body)
+	}
+
+	return template.New(name).Parse(body)
+}
+
+func isHTMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonStringContent Escapes the value to be placed between the quotes of a JSON string
+func jsonStringContent(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded[1 : len(encoded)-1])
+}
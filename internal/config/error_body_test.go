@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestErrorBodyRenderEscapesByContentType(t *testing.T) {
+	data := ErrorBodyTemplateData{
+		Status:    http.StatusServiceUnavailable,
+		Message:   "Service unavailable",
+		RequestID: `"},"admin":true,"x":"<script>alert(1)</script>\`,
+	}
+
+	t.Run("json", func(t *testing.T) {
+		errorBody := ErrorBody{Body: `{"error":"{{ .Message }}","requestId":"{{ .RequestID }}","status":{{ .Status }}}`}
+		if err := errorBody.Validate(data.Status); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		errorBody.Normalize(data.Status)
+
+		body, err := errorBody.Render(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var decoded map[string]any
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("expected a valid JSON body, got %s: %v", body, err)
+		}
+
+		if decoded["requestId"] != data.RequestID || len(decoded) != 3 {
+			t.Errorf("expected the request ID to be kept as a string, got %v", decoded)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		errorBody := ErrorBody{ContentType: "text/html; charset=utf-8", Body: `<p>{{ .RequestID }}</p>`}
+		errorBody.Normalize(data.Status)
+
+		body, err := errorBody.Render(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := `<p>&#34;},&#34;admin&#34;:true,&#34;x&#34;:&#34;&lt;script&gt;alert(1)&lt;/script&gt;\</p>`
+		if string(body) != want {
+			t.Errorf("expected %s, got %s", want, body)
+		}
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		errorBody := ErrorBody{ContentType: "text/plain", Body: `{{ .Status }} {{ .Message }}`}
+		errorBody.Normalize(data.Status)
+
+		body, err := errorBody.Render(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(body) != "503 Service unavailable" {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+}
+
+func TestErrorBodyValidate(t *testing.T) {
+	if err := (ErrorBody{Body: "{}"}).Validate(http.StatusOK); err == nil {
+		t.Error("expected an error for a non error status")
+	}
+
+	if err := (ErrorBody{Body: "{{ .Missing }}"}).Validate(http.StatusBadGateway); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	if err := (ErrorBody{ContentType: "text/html", Body: "<p>{{ .Message"}).Validate(http.StatusBadGateway); err == nil {
+		t.Error("expected an error for an unterminated action")
+	}
+}
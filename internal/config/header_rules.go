@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type HeaderRuleAction string
+
+const (
+	HeaderRuleSet    HeaderRuleAction = "set"
+	HeaderRuleAppend HeaderRuleAction = "append"
+	HeaderRuleRemove HeaderRuleAction = "remove"
+	HeaderRuleRename HeaderRuleAction = "rename"
+	HeaderRuleAllow  HeaderRuleAction = "allow"
+)
+
+var ValidHeaderRuleActions = []HeaderRuleAction{
+	HeaderRuleSet,
+	HeaderRuleAppend,
+	HeaderRuleRemove,
+	HeaderRuleRename,
+	HeaderRuleAllow,
+}
+
+var headerTemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+
+		return value
+	},
+}
+
+// HeaderTemplateData Is the data available to the header rules values templates, the user
+// fields are embedded so they can be used directly, like {{ .Properties.tenant }}
+type HeaderTemplateData struct {
+	model.User
+	RequestID string
+	ClientIP  string
+}
+
+type HeaderRule struct {
+	Action  HeaderRuleAction `yaml:"action"`
+	Name    string           `yaml:"name"`
+	Value   string           `yaml:"value"`
+	To      string           `yaml:"to"`
+	Headers []string         `yaml:"headers"`
+
+	valueTemplate *template.Template
+}
+
+func (h HeaderRule) Validate() error {
+	if !slices.Contains(ValidHeaderRuleActions, h.Action) {
+		actions := make([]string, len(ValidHeaderRuleActions))
+		for i, action := range ValidHeaderRuleActions {
+			actions[i] = string(action)
+		}
+
+		return fmt.Errorf("config 'headerRule.action' must be one of [%s]", strings.Join(actions, ", "))
+	}
+
+	switch h.Action {
+	case HeaderRuleAllow:
+		if len(h.Headers) == 0 {
+			return errors.New("config 'headerRule.headers' must be present and not be empty for the allow action")
+		}
+	case HeaderRuleRename:
+		if strings.TrimSpace(h.Name) == "" || strings.TrimSpace(h.To) == "" {
+			return errors.New("config 'headerRule.name' and 'headerRule.to' must be present and not be empty for the rename action")
+		}
+	default:
+		if strings.TrimSpace(h.Name) == "" {
+			return fmt.Errorf("config 'headerRule.name' must be present and not be empty for the %s action", h.Action)
+		}
+	}
+
+	valueTemplate, err := parseHeaderValueTemplate(h.Name, h.Value)
+	if err != nil {
+		return fmt.Errorf("config 'headerRule.value' of header %s must be a valid template: %w", h.Name, err)
+	}
+
+	// Executing against empty data catches references to unknown fields at load time
+	if err := valueTemplate.Execute(io.Discard, HeaderTemplateData{}); err != nil {
+		return fmt.Errorf("config 'headerRule.value' of header %s must be a valid template: %w", h.Name, err)
+	}
+
+	return nil
+}
+
+func (h *HeaderRule) Normalize() {
+	h.Name = http.CanonicalHeaderKey(strings.TrimSpace(h.Name))
+	h.To = http.CanonicalHeaderKey(strings.TrimSpace(h.To))
+
+	for i := range h.Headers {
+		h.Headers[i] = http.CanonicalHeaderKey(strings.TrimSpace(h.Headers[i]))
+	}
+
+	if strings.Contains(h.Value, "{{") {
+		h.valueTemplate = template.Must(parseHeaderValueTemplate(h.Name, h.Value))
+	}
+}
+
+// parseHeaderValueTemplate Parses a header rule value, missing map keys, like an unset user
+// property, are rendered as empty values
+func parseHeaderValueTemplate(name string, value string) (*template.Template, error) {
+	return template.New(name).Funcs(headerTemplateFuncs).Option("missingkey=zero").Parse(value)
+}
+
+func (h HeaderRule) renderValue(data HeaderTemplateData) (string, error) {
+	if h.valueTemplate == nil {
+		return h.Value, nil
+	}
+
+	var value bytes.Buffer
+	if err := h.valueTemplate.Execute(&value, data); err != nil {
+		return "", err
+	}
+
+	return value.String(), nil
+}
+
+type HeaderRules []HeaderRule
+
+func (h HeaderRules) Validate() error {
+	for _, rule := range h {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h HeaderRules) Normalize() {
+	for i := range h {
+		h[i].Normalize()
+	}
+}
+
+// Apply Applies the rules, in order, to the header. The source header is used by the allow
+// rules, that copy the listed headers from it, usually the client request headers
+func (h HeaderRules) Apply(header http.Header, source http.Header, data HeaderTemplateData) error {
+	for _, rule := range h {
+		switch rule.Action {
+		case HeaderRuleAllow:
+			for _, key := range rule.Headers {
+				if values := source.Values(key); len(values) > 0 {
+					header[key] = append([]string(nil), values...)
+				}
+			}
+		case HeaderRuleRemove:
+			header.Del(rule.Name)
+		case HeaderRuleRename:
+			values := header.Values(rule.Name)
+			if len(values) == 0 {
+				continue
+			}
+
+			header.Del(rule.Name)
+			header[rule.To] = append(header[rule.To], values...)
+		case HeaderRuleSet, HeaderRuleAppend:
+			value, err := rule.renderValue(data)
+			if err != nil {
+				return fmt.Errorf("failed to render header %s: %w", rule.Name, err)
+			}
+
+			// Empty values, like a missing user property, are never sent, a set rule still
+			// removes the header so it can not be spoofed by the client
+			switch {
+			case rule.Action == HeaderRuleSet && value == "":
+				header.Del(rule.Name)
+			case rule.Action == HeaderRuleSet:
+				header.Set(rule.Name, value)
+			case value != "":
+				header.Add(rule.Name, value)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+func TestHeaderRulesApply(t *testing.T) {
+	data := HeaderTemplateData{
+		User: model.User{
+			ID:         "user-id",
+			Login:      "alice",
+			Scopes:     []string{"orders.read", "orders.write"},
+			Properties: map[string]string{"tenant": "acme"},
+		},
+		RequestID: "request-id",
+		ClientIP:  "203.0.113.7",
+	}
+
+	tests := []struct {
+		name   string
+		rules  HeaderRules
+		header http.Header
+		source http.Header
+		want   http.Header
+	}{
+		{
+			name:   "set replaces every value",
+			rules:  HeaderRules{{Action: HeaderRuleSet, Name: "x-env", Value: "prod"}},
+			header: http.Header{"X-Env": {"dev", "test"}},
+			want:   http.Header{"X-Env": {"prod"}},
+		},
+		{
+			name:   "set with an empty value removes the header",
+			rules:  HeaderRules{{Action: HeaderRuleSet, Name: "X-Tenant", Value: "{{ .Properties.region }}"}},
+			header: http.Header{"X-Tenant": {"spoofed"}},
+			want:   http.Header{},
+		},
+		{
+			name:   "append adds a value",
+			rules:  HeaderRules{{Action: HeaderRuleAppend, Name: "Via", Value: "1.1 gatekeeper"}},
+			header: http.Header{"Via": {"1.1 proxy"}},
+			want:   http.Header{"Via": {"1.1 proxy", "1.1 gatekeeper"}},
+		},
+		{
+			name:   "append skips empty values",
+			rules:  HeaderRules{{Action: HeaderRuleAppend, Name: "X-Tenant", Value: "{{ .Properties.region }}"}},
+			header: http.Header{"X-Tenant": {"acme"}},
+			want:   http.Header{"X-Tenant": {"acme"}},
+		},
+		{
+			name:   "remove",
+			rules:  HeaderRules{{Action: HeaderRuleRemove, Name: "x-internal"}},
+			header: http.Header{"X-Internal": {"secret"}, "Accept": {"*/*"}},
+			want:   http.Header{"Accept": {"*/*"}},
+		},
+		{
+			name:   "rename keeps every value",
+			rules:  HeaderRules{{Action: HeaderRuleRename, Name: "X-Old", To: "X-New"}},
+			header: http.Header{"X-Old": {"a", "b"}, "X-New": {"c"}},
+			want:   http.Header{"X-New": {"c", "a", "b"}},
+		},
+		{
+			name:   "rename of a missing header",
+			rules:  HeaderRules{{Action: HeaderRuleRename, Name: "X-Old", To: "X-New"}},
+			header: http.Header{},
+			want:   http.Header{},
+		},
+		{
+			name:   "allow copies the listed source headers",
+			rules:  HeaderRules{{Action: HeaderRuleAllow, Headers: []string{"accept-language", "X-Missing"}}},
+			header: http.Header{},
+			source: http.Header{"Accept-Language": {"pt-BR", "en"}, "Cookie": {"session"}},
+			want:   http.Header{"Accept-Language": {"pt-BR", "en"}},
+		},
+		{
+			name: "templates",
+			rules: HeaderRules{
+				{Action: HeaderRuleSet, Name: "X-Tenant", Value: "{{ .Properties.tenant }}"},
+				{Action: HeaderRuleSet, Name: "X-Scopes", Value: `{{ join .Scopes "," }}`},
+				{Action: HeaderRuleSet, Name: "X-Login", Value: "{{ upper .Login }}"},
+				{Action: HeaderRuleSet, Name: "X-Region", Value: `{{ default "us" .Properties.region }}`},
+				{Action: HeaderRuleSet, Name: "X-Request", Value: "{{ .RequestID }}@{{ .ClientIP }}"},
+			},
+			header: http.Header{},
+			want: http.Header{
+				"X-Tenant":  {"acme"},
+				"X-Scopes":  {"orders.read,orders.write"},
+				"X-Login":   {"ALICE"},
+				"X-Region":  {"us"},
+				"X-Request": {"request-id@203.0.113.7"},
+			},
+		},
+		{
+			name: "rules run in order",
+			rules: HeaderRules{
+				{Action: HeaderRuleSet, Name: "X-Temp", Value: "{{ .ID }}"},
+				{Action: HeaderRuleRename, Name: "X-Temp", To: "X-User"},
+				{Action: HeaderRuleAppend, Name: "X-User", Value: "extra"},
+			},
+			header: http.Header{},
+			want:   http.Header{"X-User": {"user-id", "extra"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.rules.Validate(); err != nil {
+				t.Fatalf("unexpected config error: %v", err)
+			}
+			test.rules.Normalize()
+
+			if err := test.rules.Apply(test.header, test.source, data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(test.header) != len(test.want) {
+				t.Fatalf("expected %v, got %v", test.want, test.header)
+			}
+
+			for key, values := range test.want {
+				if !slices.Equal(test.header[key], values) {
+					t.Errorf("expected %s=%v, got %v", key, values, test.header[key])
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    HeaderRule
+		wantErr bool
+	}{
+		{"set", HeaderRule{Action: HeaderRuleSet, Name: "X-A", Value: "a"}, false},
+		{"unknown action", HeaderRule{Action: "replace", Name: "X-A"}, true},
+		{"set without name", HeaderRule{Action: HeaderRuleSet, Value: "a"}, true},
+		{"rename without target", HeaderRule{Action: HeaderRuleRename, Name: "X-A"}, true},
+		{"allow without headers", HeaderRule{Action: HeaderRuleAllow}, true},
+		{"invalid template", HeaderRule{Action: HeaderRuleSet, Name: "X-A", Value: "{{ .Login "}, true},
+		{"unknown field", HeaderRule{Action: HeaderRuleSet, Name: "X-A", Value: "{{ .Password2 }}"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.rule.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("expected error=%t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHealthCheckMethod             = http.MethodGet
+	defaultHealthCheckIntervalSeconds    = 10
+	defaultHealthCheckTimeoutSeconds     = 5
+	defaultHealthCheckHealthyThreshold   = 2
+	defaultHealthCheckUnhealthyThreshold = 3
+)
+
+type HealthCheck struct {
+	Path               string `yaml:"path"`
+	Method             string `yaml:"method"`
+	IntervalSeconds    int    `yaml:"intervalSeconds"`
+	TimeoutSeconds     int    `yaml:"timeoutSeconds"`
+	ExpectedStatuses   []int  `yaml:"expectedStatuses"`
+	HealthyThreshold   int    `yaml:"healthyThreshold"`
+	UnhealthyThreshold int    `yaml:"unhealthyThreshold"`
+}
+
+func (h HealthCheck) IsEnabled() bool {
+	return strings.TrimSpace(h.Path) != ""
+}
+
+func (h HealthCheck) Validate() error {
+	if !h.IsEnabled() {
+		return nil
+	}
+
+	if !strings.HasPrefix(h.Path, "/") {
+		return errors.New("config 'healthCheck.path' must be an absolute path")
+	}
+
+	if h.IntervalSeconds < 0 || h.TimeoutSeconds < 0 {
+		return errors.New("config 'healthCheck.intervalSeconds' and 'healthCheck.timeoutSeconds' must not be negative")
+	}
+
+	if h.HealthyThreshold < 0 || h.UnhealthyThreshold < 0 {
+		return errors.New("config 'healthCheck.healthyThreshold' and 'healthCheck.unhealthyThreshold' must not be negative")
+	}
+
+	for _, status := range h.ExpectedStatuses {
+		if status < 100 || status > 599 {
+			return errors.New("config 'healthCheck.expectedStatuses' must only contain valid HTTP status codes")
+		}
+	}
+
+	return nil
+}
+
+func (h *HealthCheck) Normalize() {
+	if !h.IsEnabled() {
+		return
+	}
+
+	h.Method = strings.ToUpper(h.Method)
+	if h.Method == "" {
+		h.Method = defaultHealthCheckMethod
+	}
+
+	if h.IntervalSeconds == 0 {
+		h.IntervalSeconds = defaultHealthCheckIntervalSeconds
+	}
+
+	if h.TimeoutSeconds == 0 {
+		h.TimeoutSeconds = defaultHealthCheckTimeoutSeconds
+	}
+
+	if h.HealthyThreshold == 0 {
+		h.HealthyThreshold = defaultHealthCheckHealthyThreshold
+	}
+
+	if h.UnhealthyThreshold == 0 {
+		h.UnhealthyThreshold = defaultHealthCheckUnhealthyThreshold
+	}
+}
+
+func (h HealthCheck) Interval() time.Duration {
+	return time.Duration(h.IntervalSeconds) * time.Second
+}
+
+func (h HealthCheck) Timeout() time.Duration {
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// IsExpectedStatus Reports if the status is a healthy one, when no statuses are configured
+// any 2xx or 3xx status is considered healthy
+func (h HealthCheck) IsExpectedStatus(status int) bool {
+	if len(h.ExpectedStatuses) == 0 {
+		return status >= 200 && status < 400
+	}
+
+	return slices.Contains(h.ExpectedStatuses, status)
+}
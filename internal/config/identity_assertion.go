@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIdentityAssertionHeader            = "X-Api-Gatekeeper-Assertion"
+	defaultIdentityAssertionIssuer            = "api-gatekeeper"
+	defaultIdentityAssertionKeyID             = "api-gatekeeper"
+	defaultIdentityAssertionExpirationSeconds = 60
+)
+
+// IdentityAssertion Configures the signed JWT that is sent to the backends on every authenticated
+// request, so they can verify that the request came through the gatekeeper
+type IdentityAssertion struct {
+	Enabled           bool     `yaml:"enabled"`
+	Header            string   `yaml:"header"`
+	Issuer            string   `yaml:"issuer"`
+	KeyID             string   `yaml:"keyId"`
+	PrivateKeyPath    string   `yaml:"privateKeyPath"`
+	ExpirationSeconds int      `yaml:"expirationSeconds"`
+	Properties        []string `yaml:"properties"`
+}
+
+func (i IdentityAssertion) Validate() error {
+	if i.ExpirationSeconds < 0 {
+		return errors.New("config 'identityAssertion.expirationSeconds' must not be negative")
+	}
+
+	return nil
+}
+
+func (i *IdentityAssertion) Normalize() {
+	if !i.Enabled {
+		return
+	}
+
+	if strings.TrimSpace(i.Header) == "" {
+		i.Header = defaultIdentityAssertionHeader
+	}
+
+	i.Header = http.CanonicalHeaderKey(i.Header)
+
+	if strings.TrimSpace(i.Issuer) == "" {
+		i.Issuer = defaultIdentityAssertionIssuer
+	}
+
+	if strings.TrimSpace(i.KeyID) == "" {
+		i.KeyID = defaultIdentityAssertionKeyID
+	}
+
+	if i.ExpirationSeconds == 0 {
+		i.ExpirationSeconds = defaultIdentityAssertionExpirationSeconds
+	}
+}
+
+func (i IdentityAssertion) Expiration() time.Duration {
+	return time.Duration(i.ExpirationSeconds) * time.Second
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// IPFilter Is a parsed pair of ipAllow and ipDeny lists. A denied address is always rejected,
+// and when the allow list is not empty only the addresses in it are accepted
+type IPFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+func newIPFilter(allow, deny []string) IPFilter {
+	return IPFilter{
+		allow: parsePrefixes(allow),
+		deny:  parsePrefixes(deny),
+	}
+}
+
+// IsEmpty Returns if the filter accepts every address
+func (f IPFilter) IsEmpty() bool {
+	return len(f.allow) == 0 && len(f.deny) == 0
+}
+
+// Allows Checks the address against the filter. Invalid addresses, like unresolved or
+// obfuscated forwarded clients, can not be checked, so they are only accepted by empty filters
+func (f IPFilter) Allows(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return f.IsEmpty()
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return true
+	}
+
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateIPFilter(config string, allow, deny []string) error {
+	for _, value := range allow {
+		if _, err := parsePrefix(value); err != nil {
+			return fmt.Errorf("config '%s.ipAllow' must only contain IP addresses or CIDR ranges, got %s", config, value)
+		}
+	}
+
+	for _, value := range deny {
+		if _, err := parsePrefix(value); err != nil {
+			return fmt.Errorf("config '%s.ipDeny' must only contain IP addresses or CIDR ranges, got %s", config, value)
+		}
+	}
+
+	return nil
+}
+
+// parsePrefixes Parses IP addresses and CIDR ranges, ignoring the invalid ones
+func parsePrefixes(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, err := parsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes
+}
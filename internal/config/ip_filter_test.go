@@ -0,0 +1,58 @@
+package config
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIPFilterAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		addr  string
+		want  bool
+	}{
+		{"empty filter", nil, nil, "203.0.113.7", true},
+		{"empty filter with invalid address", nil, nil, "", true},
+		{"allowed range", []string{"10.0.0.0/8"}, nil, "10.1.2.3", true},
+		{"outside allowed range", []string{"10.0.0.0/8"}, nil, "203.0.113.7", false},
+		{"allowed single address", []string{"203.0.113.7"}, nil, "203.0.113.7", true},
+		{"denied range", nil, []string{"198.51.100.0/24"}, "198.51.100.9", false},
+		{"outside denied range", nil, []string{"198.51.100.0/24"}, "203.0.113.7", true},
+		{"deny takes precedence", []string{"10.0.0.0/8"}, []string{"10.6.6.6"}, "10.6.6.6", false},
+		{"ipv6 allowed", []string{"2001:db8::/32"}, nil, "2001:db8::1", true},
+		{"ipv6 outside allowed", []string{"2001:db8::/32"}, nil, "2001:db9::1", false},
+		{"ipv4 mapped ipv6 allowed", []string{"10.0.0.0/8"}, nil, "::ffff:10.1.2.3", true},
+		{"ipv4 mapped ipv6 denied", nil, []string{"198.51.100.0/24"}, "::ffff:198.51.100.9", false},
+		{"invalid address with allow list", []string{"10.0.0.0/8"}, nil, "", false},
+		{"invalid address with deny list", nil, []string{"198.51.100.0/24"}, "", false},
+		{"unknown node with deny list", nil, []string{"198.51.100.0/24"}, "unknown", false},
+		{"obfuscated node with deny list", nil, []string{"198.51.100.0/24"}, "_hidden", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := newIPFilter(test.allow, test.deny)
+			addr, _ := netip.ParseAddr(test.addr)
+
+			if got := filter.Allows(addr); got != test.want {
+				t.Errorf("expected %t for %q, got %t", test.want, test.addr, got)
+			}
+		})
+	}
+}
+
+func TestValidateIPFilter(t *testing.T) {
+	if err := validateIPFilter("api", []string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32"}, []string{"::1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := validateIPFilter("api", []string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("expected an error for an invalid allow range")
+	}
+
+	if err := validateIPFilter("api", nil, []string{"example.com"}); err == nil {
+		t.Error("expected an error for an invalid deny address")
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type LoadBalancingStrategy string
+
+const (
+	LoadBalancingRoundRobin       LoadBalancingStrategy = "round-robin"
+	LoadBalancingWeighted         LoadBalancingStrategy = "weighted"
+	LoadBalancingLeastConnections LoadBalancingStrategy = "least-connections"
+	LoadBalancingRandomTwoChoices LoadBalancingStrategy = "random-two-choices"
+	LoadBalancingConsistentHash   LoadBalancingStrategy = "consistent-hash"
+)
+
+var ValidLoadBalancingStrategies = []LoadBalancingStrategy{
+	LoadBalancingRoundRobin,
+	LoadBalancingWeighted,
+	LoadBalancingLeastConnections,
+	LoadBalancingRandomTwoChoices,
+	LoadBalancingConsistentHash,
+}
+
+const (
+	HashOnUser   = "user"
+	HashOnHeader = "header"
+)
+
+type Target struct {
+	Host   string `yaml:"host"`
+	Weight int    `yaml:"weight"`
+}
+
+func (t Target) Validate() error {
+	if strings.TrimSpace(t.Host) == "" {
+		return errors.New("config 'target.host' must be present and not be empty")
+	}
+
+	if t.Weight < 0 {
+		return errors.New("config 'target.weight' must not be negative")
+	}
+
+	return nil
+}
+
+func (t *Target) Normalize() {
+	if t.Weight == 0 {
+		t.Weight = 1
+	}
+}
+
+type LoadBalancing struct {
+	Strategy   LoadBalancingStrategy `yaml:"strategy"`
+	HashOn     string                `yaml:"hashOn"`
+	HashHeader string                `yaml:"hashHeader"`
+}
+
+func (l LoadBalancing) Validate() error {
+	if l.Strategy != "" && !slices.Contains(ValidLoadBalancingStrategies, l.Strategy) {
+		strategies := make([]string, len(ValidLoadBalancingStrategies))
+		for i, strategy := range ValidLoadBalancingStrategies {
+			strategies[i] = string(strategy)
+		}
+
+		return fmt.Errorf("config 'loadBalancing.strategy' must be one of [%s]", strings.Join(strategies, ", "))
+	}
+
+	if l.HashOn != "" && l.HashOn != HashOnUser && l.HashOn != HashOnHeader {
+		return fmt.Errorf("config 'loadBalancing.hashOn' must be one of [%s, %s]", HashOnUser, HashOnHeader)
+	}
+
+	if l.HashOn == HashOnHeader && strings.TrimSpace(l.HashHeader) == "" {
+		return errors.New("config 'loadBalancing.hashHeader' must be present when 'loadBalancing.hashOn' is header")
+	}
+
+	return nil
+}
+
+func (l *LoadBalancing) Normalize() {
+	if l.Strategy == "" {
+		l.Strategy = LoadBalancingRoundRobin
+	}
+
+	if l.Strategy == LoadBalancingConsistentHash && l.HashOn == "" {
+		l.HashOn = HashOnUser
+	}
+}
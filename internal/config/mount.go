@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const mountWildcardVariable = "{path...}"
+
+func (b Backend) IsMounted() bool {
+	return strings.TrimSpace(b.MountPath) != ""
+}
+
+func (b Backend) validateMount() error {
+	if !b.IsMounted() {
+		return nil
+	}
+
+	if !strings.HasPrefix(b.MountPath, "/") || !strings.HasSuffix(b.MountPath, "/") {
+		return errors.New("config 'backend.mountPath' must start and end with /")
+	}
+
+	if strings.HasPrefix(strings.ToLower(b.MountPath), "/api-gatekeeper/") {
+		return errors.New("config 'backend.mountPath' should not start with /api-gatekeeper, this is a reserved route namespace")
+	}
+
+	if strings.ContainsAny(b.MountPath, "{}") {
+		return errors.New("config 'backend.mountPath' must not contain path variables")
+	}
+
+	if b.ReplacePrefix != "" && (!strings.HasPrefix(b.ReplacePrefix, "/") || !strings.HasSuffix(b.ReplacePrefix, "/")) {
+		return errors.New("config 'backend.replacePrefix' must start and end with /")
+	}
+
+	if b.MountTimeoutSeconds < 0 {
+		return errors.New("config 'backend.mountTimeoutSeconds' must not be negative")
+	}
+
+	for _, route := range b.Routes {
+		if route.BackendPath == "" && !strings.HasPrefix(route.GatekeeperPath, b.MountPath) {
+			return fmt.Errorf("config 'route.gatekeeperPath' %s must be under the backend 'mountPath' %s when 'route.backendPath' is omitted", route.GatekeeperPath, b.MountPath)
+		}
+	}
+
+	return nil
+}
+
+// MountedBackendPath Returns the backend path of a path under the backend mount path, stripping
+// or replacing the mount prefix as configured
+func (b Backend) MountedBackendPath(gatekeeperPath string) string {
+	subPath := strings.TrimPrefix(gatekeeperPath, b.MountPath)
+
+	switch {
+	case b.ReplacePrefix != "":
+		return b.ReplacePrefix + subPath
+	case b.StripPrefix:
+		return "/" + subPath
+	}
+
+	return b.MountPath + subPath
+}
+
+// normalizeMount Fills the backend path of the routes under the mount path that only override
+// its settings and adds the catch-all route of the mount path
+func (b *Backend) normalizeMount() {
+	if !b.IsMounted() {
+		return
+	}
+
+	for i := range b.Routes {
+		if b.Routes[i].BackendPath == "" {
+			b.Routes[i].BackendPath = b.MountedBackendPath(b.Routes[i].GatekeeperPath)
+		}
+	}
+}
+
+// MountRoute Returns the catch-all route that proxies every request under the mount path, of any
+// method, to the backend
+func (b Backend) MountRoute() Route {
+	return Route{
+		Type:           RouteTypeHTTP,
+		GatekeeperPath: b.MountPath + mountWildcardVariable,
+		BackendPath:    b.MountedBackendPath(b.MountPath + mountWildcardVariable),
+		TimeoutSeconds: b.MountTimeoutSeconds,
+		Scopes:         make([]string, 0),
+		Headers:        make(map[string]string),
+	}
+}
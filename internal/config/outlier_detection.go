@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultOutlierDetectionEjectionSeconds = 30
+
+type OutlierDetection struct {
+	ConsecutiveFailures int `yaml:"consecutiveFailures"`
+	EjectionSeconds     int `yaml:"ejectionSeconds"`
+}
+
+func (o OutlierDetection) IsEnabled() bool {
+	return o.ConsecutiveFailures > 0
+}
+
+func (o OutlierDetection) Validate() error {
+	if o.ConsecutiveFailures < 0 || o.EjectionSeconds < 0 {
+		return errors.New("config 'outlierDetection.consecutiveFailures' and 'outlierDetection.ejectionSeconds' must not be negative")
+	}
+
+	return nil
+}
+
+func (o *OutlierDetection) Normalize() {
+	if o.IsEnabled() && o.EjectionSeconds == 0 {
+		o.EjectionSeconds = defaultOutlierDetectionEjectionSeconds
+	}
+}
+
+func (o OutlierDetection) EjectionDuration() time.Duration {
+	return time.Duration(o.EjectionSeconds) * time.Second
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type BackendProtocol string
+
+const (
+	BackendProtocolAuto  BackendProtocol = "auto"
+	BackendProtocolHTTP1 BackendProtocol = "http1"
+	BackendProtocolHTTP2 BackendProtocol = "http2"
+	BackendProtocolH2C   BackendProtocol = "h2c"
+)
+
+var ValidBackendProtocols = []BackendProtocol{
+	BackendProtocolAuto,
+	BackendProtocolHTTP1,
+	BackendProtocolHTTP2,
+	BackendProtocolH2C,
+}
+
+func (p BackendProtocol) Validate() error {
+	if p == "" || slices.Contains(ValidBackendProtocols, p) {
+		return nil
+	}
+
+	protocols := make([]string, len(ValidBackendProtocols))
+	for i, protocol := range ValidBackendProtocols {
+		protocols[i] = string(protocol)
+	}
+
+	return fmt.Errorf("config 'backend.protocol' must be one of [%s]", strings.Join(protocols, ", "))
+}
+
+type RouteType string
+
+const (
+	RouteTypeHTTP RouteType = "http"
+	RouteTypeGRPC RouteType = "grpc"
+)
+
+func (t RouteType) Validate() error {
+	if t == "" || t == RouteTypeHTTP || t == RouteTypeGRPC {
+		return nil
+	}
+
+	return fmt.Errorf("config 'route.type' must be one of [%s, %s]", RouteTypeHTTP, RouteTypeGRPC)
+}
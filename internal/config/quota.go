@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type QuotaPeriod string
+
+const (
+	QuotaPeriodDay   QuotaPeriod = "day"
+	QuotaPeriodWeek  QuotaPeriod = "week"
+	QuotaPeriodMonth QuotaPeriod = "month"
+)
+
+var ValidQuotaPeriods = []QuotaPeriod{
+	QuotaPeriodDay,
+	QuotaPeriodWeek,
+	QuotaPeriodMonth,
+}
+
+// Quota Is a long window allowance of calls per user, shared by every backend and route that
+// references it by name. The periods follow the UTC calendar, weeks starting on monday
+type Quota struct {
+	Name                 string      `yaml:"name"`
+	Period               QuotaPeriod `yaml:"period"`
+	Calls                int         `yaml:"calls"`
+	UserOverrideProperty string      `yaml:"userOverrideProperty"`
+}
+
+func (q Quota) Validate() error {
+	if strings.TrimSpace(q.Name) == "" {
+		return errors.New("config 'quota.name' must be present and not be empty")
+	}
+
+	if !slices.Contains(ValidQuotaPeriods, q.Period) {
+		periods := make([]string, len(ValidQuotaPeriods))
+		for i, period := range ValidQuotaPeriods {
+			periods[i] = string(period)
+		}
+
+		return fmt.Errorf("config 'quota.period' must be one of [%s]", strings.Join(periods, ", "))
+	}
+
+	if q.Calls <= 0 {
+		return errors.New("config 'quota.calls' must be greater than zero")
+	}
+
+	return nil
+}
+
+// PeriodBounds Returns the start and end of the quota period containing the time
+func (q Quota) PeriodBounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch q.Period {
+	case QuotaPeriodWeek:
+		start := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	case QuotaPeriodMonth:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+type Quotas []Quota
+
+func (q Quotas) Validate() error {
+	names := make(map[string]bool, len(q))
+	for _, quota := range q {
+		if err := quota.Validate(); err != nil {
+			return err
+		}
+
+		if names[quota.Name] {
+			return fmt.Errorf("config 'quotas' must not have duplicated names, got %s", quota.Name)
+		}
+
+		names[quota.Name] = true
+	}
+
+	return nil
+}
+
+// Get Returns the quota with the name
+func (q Quotas) Get(name string) (Quota, bool) {
+	for _, quota := range q {
+		if quota.Name == name {
+			return quota, true
+		}
+	}
+
+	return Quota{}, false
+}
+
+// validateReferences Checks if every quota name referenced by the backends and routes exists
+func (q Quotas) validateReferences(backends []Backend) error {
+	for _, backend := range backends {
+		for _, name := range backend.Quotas {
+			if _, exists := q.Get(name); !exists {
+				return fmt.Errorf("config 'backend.quotas' must only reference existing quotas, got %s", name)
+			}
+		}
+
+		for _, route := range backend.Routes {
+			for _, name := range route.Quotas {
+				if _, exists := q.Get(name); !exists {
+					return fmt.Errorf("config 'route.quotas' must only reference existing quotas, got %s", name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriodBounds(t *testing.T) {
+	saoPaulo := time.FixedZone("UTC-3", -3*60*60)
+
+	tests := []struct {
+		name      string
+		period    QuotaPeriod
+		at        time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "day",
+			period:    QuotaPeriodDay,
+			at:        time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "day follows the UTC calendar",
+			period:    QuotaPeriodDay,
+			at:        time.Date(2026, 3, 10, 22, 0, 0, 0, saoPaulo),
+			wantStart: time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "week starts on monday",
+			period:    QuotaPeriodWeek,
+			at:        time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "week on sunday",
+			period:    QuotaPeriodWeek,
+			at:        time.Date(2026, 3, 15, 23, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "week on monday midnight",
+			period:    QuotaPeriodWeek,
+			at:        time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month",
+			period:    QuotaPeriodMonth,
+			at:        time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month crossing the year",
+			period:    QuotaPeriodMonth,
+			at:        time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end := Quota{Period: test.period}.PeriodBounds(test.at)
+			if !start.Equal(test.wantStart) || !end.Equal(test.wantEnd) {
+				t.Errorf("expected [%s, %s), got [%s, %s)", test.wantStart, test.wantEnd, start, end)
+			}
+		})
+	}
+}
+
+func TestQuotasValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		quotas  Quotas
+		wantErr bool
+	}{
+		{"valid", Quotas{{Name: "a", Period: QuotaPeriodDay, Calls: 1}}, false},
+		{"missing name", Quotas{{Period: QuotaPeriodDay, Calls: 1}}, true},
+		{"invalid period", Quotas{{Name: "a", Period: "year", Calls: 1}}, true},
+		{"no calls", Quotas{{Name: "a", Period: QuotaPeriodDay}}, true},
+		{"duplicated names", Quotas{{Name: "a", Period: QuotaPeriodDay, Calls: 1}, {Name: "a", Period: QuotaPeriodMonth, Calls: 1}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.quotas.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("expected error=%t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestQuotasValidateReferences(t *testing.T) {
+	quotas := Quotas{{Name: "monthly", Period: QuotaPeriodMonth, Calls: 1}}
+
+	valid := []Backend{{Quotas: []string{"monthly"}, Routes: []Route{{Quotas: []string{"monthly"}}}}}
+	if err := quotas.validateReferences(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	unknownOnBackend := []Backend{{Quotas: []string{"daily"}}}
+	if err := quotas.validateReferences(unknownOnBackend); err == nil {
+		t.Error("expected an error for an unknown backend quota")
+	}
+
+	unknownOnRoute := []Backend{{Routes: []Route{{Quotas: []string{"daily"}}}}}
+	if err := quotas.validateReferences(unknownOnRoute); err == nil {
+		t.Error("expected an error for an unknown route quota")
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type RateLimitKey string
+
+const (
+	RateLimitKeyUser   RateLimitKey = "user"
+	RateLimitKeyIP     RateLimitKey = "ip"
+	RateLimitKeyHeader RateLimitKey = "header"
+	RateLimitKeyAPIKey RateLimitKey = "api-key"
+)
+
+var ValidRateLimitKeys = []RateLimitKey{
+	RateLimitKeyUser,
+	RateLimitKeyIP,
+	RateLimitKeyHeader,
+	RateLimitKeyAPIKey,
+}
+
+type RateLimitAlgorithm string
+
+const (
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding-window"
+	RateLimitFixedWindow   RateLimitAlgorithm = "fixed-window"
+)
+
+var ValidRateLimitAlgorithms = []RateLimitAlgorithm{
+	RateLimitSlidingWindow,
+	RateLimitFixedWindow,
+}
+
+const defaultRateLimitAPIKeyHeader = "X-Api-Key"
+
+type RateLimit struct {
+	Name                 string             `yaml:"name"`
+	KeyBy                RateLimitKey       `yaml:"keyBy"`
+	Header               string             `yaml:"header"`
+	Algorithm            RateLimitAlgorithm `yaml:"algorithm"`
+	Requests             int                `yaml:"requests"`
+	WindowSeconds        int                `yaml:"windowSeconds"`
+	UserOverrideProperty string             `yaml:"userOverrideProperty"`
+}
+
+func (r RateLimit) Validate() error {
+	if r.KeyBy != "" && !slices.Contains(ValidRateLimitKeys, r.KeyBy) {
+		keys := make([]string, len(ValidRateLimitKeys))
+		for i, key := range ValidRateLimitKeys {
+			keys[i] = string(key)
+		}
+
+		return fmt.Errorf("config 'rateLimit.keyBy' must be one of [%s]", strings.Join(keys, ", "))
+	}
+
+	if r.KeyBy == RateLimitKeyHeader && strings.TrimSpace(r.Header) == "" {
+		return errors.New("config 'rateLimit.header' must be present and not be empty when 'rateLimit.keyBy' is header")
+	}
+
+	if r.Algorithm != "" && !slices.Contains(ValidRateLimitAlgorithms, r.Algorithm) {
+		algorithms := make([]string, len(ValidRateLimitAlgorithms))
+		for i, algorithm := range ValidRateLimitAlgorithms {
+			algorithms[i] = string(algorithm)
+		}
+
+		return fmt.Errorf("config 'rateLimit.algorithm' must be one of [%s]", strings.Join(algorithms, ", "))
+	}
+
+	if r.Requests <= 0 || r.WindowSeconds <= 0 {
+		return errors.New("config 'rateLimit.requests' and 'rateLimit.windowSeconds' must be greater than zero")
+	}
+
+	return nil
+}
+
+func (r *RateLimit) Normalize(index int) {
+	if r.KeyBy == "" {
+		r.KeyBy = RateLimitKeyUser
+	}
+
+	if r.KeyBy == RateLimitKeyAPIKey && strings.TrimSpace(r.Header) == "" {
+		r.Header = defaultRateLimitAPIKeyHeader
+	}
+
+	r.Header = http.CanonicalHeaderKey(strings.TrimSpace(r.Header))
+
+	if r.Algorithm == "" {
+		r.Algorithm = RateLimitSlidingWindow
+	}
+
+	if strings.TrimSpace(r.Name) == "" {
+		r.Name = fmt.Sprintf("%s-%d", r.KeyBy, index)
+	}
+}
+
+// IsClientKeyed Reports if the limit is keyed by the client itself, by its IP or a header, so it
+// can be checked before the authentication
+func (r RateLimit) IsClientKeyed() bool {
+	return r.KeyBy != RateLimitKeyUser
+}
+
+func (r RateLimit) Window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+type RateLimits []RateLimit
+
+func (r RateLimits) Validate() error {
+	for _, rateLimit := range r {
+		if err := rateLimit.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r RateLimits) Normalize() {
+	for i := range r {
+		r[i].Normalize(i)
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type ResponseHeaders struct {
+	Allow  []string          `yaml:"allow"`
+	Deny   []string          `yaml:"deny"`
+	Rename map[string]string `yaml:"rename"`
+	Add    map[string]string `yaml:"add"`
+}
+
+func (h ResponseHeaders) Validate() error {
+	for _, header := range append(append([]string{}, h.Allow...), h.Deny...) {
+		if strings.TrimSpace(header) == "" {
+			return errors.New("config 'responseHeaders.allow' and 'responseHeaders.deny' must not contain empty header names")
+		}
+	}
+
+	for from, to := range h.Rename {
+		if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return errors.New("config 'responseHeaders.rename' must not contain empty header names")
+		}
+	}
+
+	for header := range h.Add {
+		if strings.TrimSpace(header) == "" {
+			return errors.New("config 'responseHeaders.add' must not contain empty header names")
+		}
+	}
+
+	return nil
+}
+
+func (h *ResponseHeaders) Normalize() {
+	for i := range h.Allow {
+		h.Allow[i] = http.CanonicalHeaderKey(strings.TrimSpace(h.Allow[i]))
+	}
+
+	for i := range h.Deny {
+		h.Deny[i] = http.CanonicalHeaderKey(strings.TrimSpace(h.Deny[i]))
+	}
+
+	rename := make(map[string]string, len(h.Rename))
+	for from, to := range h.Rename {
+		rename[http.CanonicalHeaderKey(strings.TrimSpace(from))] = http.CanonicalHeaderKey(strings.TrimSpace(to))
+	}
+	h.Rename = rename
+
+	add := make(map[string]string, len(h.Add))
+	for header, value := range h.Add {
+		add[http.CanonicalHeaderKey(strings.TrimSpace(header))] = value
+	}
+	h.Add = add
+}
+
+// Apply Filters the header in place following the rules order: allow, deny, rename and add
+func (h ResponseHeaders) Apply(header http.Header) {
+	if len(h.Allow) > 0 {
+		allowed := make(map[string]bool, len(h.Allow))
+		for _, key := range h.Allow {
+			allowed[http.CanonicalHeaderKey(key)] = true
+		}
+
+		for key := range header {
+			if !allowed[http.CanonicalHeaderKey(key)] {
+				delete(header, key)
+			}
+		}
+	}
+
+	for _, key := range h.Deny {
+		header.Del(key)
+	}
+
+	for from, to := range h.Rename {
+		values := header.Values(from)
+		if len(values) == 0 {
+			continue
+		}
+
+		header.Del(from)
+		for _, value := range values {
+			header.Add(to, value)
+		}
+	}
+
+	for key, value := range h.Add {
+		header.Set(key, value)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	RetryOnConnect = "connect"
+	RetryOnReset   = "reset"
+	RetryOnTimeout = "timeout"
+)
+
+var ValidRetryOnErrors = []string{
+	RetryOnConnect,
+	RetryOnReset,
+	RetryOnTimeout,
+}
+
+const (
+	defaultRetryInitialBackoffMilliseconds = 100
+	defaultRetryMaxBackoffMilliseconds     = 2000
+	defaultRetryBodyBufferBytes            = 64 * 1024
+)
+
+var (
+	defaultRetryOnStatuses = []int{502, 503, 504}
+	defaultRetryOnErrors   = []string{RetryOnConnect, RetryOnReset}
+)
+
+type Retry struct {
+	MaxAttempts                int      `yaml:"maxAttempts"`
+	RetryOnStatuses            []int    `yaml:"retryOnStatuses"`
+	RetryOnErrors              []string `yaml:"retryOnErrors"`
+	InitialBackoffMilliseconds int      `yaml:"initialBackoffMilliseconds"`
+	MaxBackoffMilliseconds     int      `yaml:"maxBackoffMilliseconds"`
+	RetryNonIdempotent         bool     `yaml:"retryNonIdempotent"`
+	BodyBufferBytes            int      `yaml:"bodyBufferBytes"`
+}
+
+func (r Retry) IsEnabled() bool {
+	return r.MaxAttempts > 1
+}
+
+func (r Retry) Validate() error {
+	if r.MaxAttempts < 0 {
+		return errors.New("config 'retry.maxAttempts' must not be negative")
+	}
+
+	if r.InitialBackoffMilliseconds < 0 || r.MaxBackoffMilliseconds < 0 || r.BodyBufferBytes < 0 {
+		return errors.New("config 'retry' backoffs and buffer sizes must not be negative")
+	}
+
+	for _, status := range r.RetryOnStatuses {
+		if status < 100 || status > 599 {
+			return errors.New("config 'retry.retryOnStatuses' must only contain valid HTTP status codes")
+		}
+	}
+
+	for _, errorClass := range r.RetryOnErrors {
+		if !slices.Contains(ValidRetryOnErrors, errorClass) {
+			return fmt.Errorf("config 'retry.retryOnErrors' must only contain [%s]", strings.Join(ValidRetryOnErrors, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (r *Retry) Normalize() {
+	if !r.IsEnabled() {
+		return
+	}
+
+	if r.RetryOnStatuses == nil {
+		r.RetryOnStatuses = slices.Clone(defaultRetryOnStatuses)
+	}
+
+	if r.RetryOnErrors == nil {
+		r.RetryOnErrors = slices.Clone(defaultRetryOnErrors)
+	}
+
+	if r.InitialBackoffMilliseconds == 0 {
+		r.InitialBackoffMilliseconds = defaultRetryInitialBackoffMilliseconds
+	}
+
+	if r.MaxBackoffMilliseconds == 0 {
+		r.MaxBackoffMilliseconds = defaultRetryMaxBackoffMilliseconds
+	}
+
+	if r.BodyBufferBytes == 0 {
+		r.BodyBufferBytes = defaultRetryBodyBufferBytes
+	}
+}
+
+func (r Retry) InitialBackoff() time.Duration {
+	return time.Duration(r.InitialBackoffMilliseconds) * time.Millisecond
+}
+
+func (r Retry) MaxBackoff() time.Duration {
+	return time.Duration(r.MaxBackoffMilliseconds) * time.Millisecond
+}
+
+// RetryPolicy Returns the retry policy of the route, the route "retry" overrides the backend one
+func (r Route) RetryPolicy(backend Backend) Retry {
+	if r.Retry.MaxAttempts > 0 {
+		return r.Retry
+	}
+
+	return backend.Retry
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrMissingRouteVariable = errors.New("missing route variable")
+
+type Rewrite struct {
+	Regex       string            `yaml:"regex"`
+	Variables   map[string]string `yaml:"variables"`
+	QueryToPath map[string]string `yaml:"queryToPath"`
+	StripPrefix string            `yaml:"stripPrefix"`
+	AddPrefix   string            `yaml:"addPrefix"`
+
+	compiledRegex *regexp.Regexp
+}
+
+func (r Rewrite) Validate() error {
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("config 'rewrite.regex' must be a valid regular expression: %w", err)
+		}
+	}
+
+	if r.StripPrefix != "" && !strings.HasPrefix(r.StripPrefix, "/") {
+		return errors.New("config 'rewrite.stripPrefix' must start with /")
+	}
+
+	if r.AddPrefix != "" && !strings.HasPrefix(r.AddPrefix, "/") {
+		return errors.New("config 'rewrite.addPrefix' must start with /")
+	}
+
+	for backendVariable, sourceVariable := range r.Variables {
+		if strings.TrimSpace(backendVariable) == "" || strings.TrimSpace(sourceVariable) == "" {
+			return errors.New("config 'rewrite.variables' must not contain empty variable names")
+		}
+
+		// The aliases are resolved in no particular order, so they can only point to the route
+		// variables, regex groups and query parameters, never to another alias
+		if _, isAlias := r.Variables[sourceVariable]; isAlias {
+			return fmt.Errorf("config 'rewrite.variables' variable %s must not alias another alias, got %s", backendVariable, sourceVariable)
+		}
+	}
+
+	queryBackendVariables := make(map[string]bool, len(r.QueryToPath))
+	for queryParam, backendVariable := range r.QueryToPath {
+		if strings.TrimSpace(queryParam) == "" || strings.TrimSpace(backendVariable) == "" {
+			return errors.New("config 'rewrite.queryToPath' must not contain empty names")
+		}
+
+		if queryBackendVariables[backendVariable] {
+			return fmt.Errorf("config 'rewrite.queryToPath' must not move two query parameters to the same variable, got %s", backendVariable)
+		}
+
+		queryBackendVariables[backendVariable] = true
+	}
+
+	return nil
+}
+
+func (r *Rewrite) Normalize() {
+	if r.Regex != "" {
+		r.compiledRegex = regexp.MustCompile(r.Regex)
+	}
+}
+
+// availableVariables Returns the name of every variable that can be used on the backend path
+func (r Rewrite) availableVariables(gatekeeperVariables []RouteVariable) map[string]bool {
+	available := make(map[string]bool)
+
+	for _, variable := range gatekeeperVariables {
+		available[variable.Name()] = true
+	}
+
+	if r.compiledRegex != nil {
+		for i, name := range r.compiledRegex.SubexpNames() {
+			available[strconv.Itoa(i)] = true
+			if name != "" {
+				available[name] = true
+			}
+		}
+	}
+
+	for _, backendVariable := range r.QueryToPath {
+		available[backendVariable] = true
+	}
+
+	for backendVariable, sourceVariable := range r.Variables {
+		if available[sourceVariable] {
+			available[backendVariable] = true
+		}
+	}
+
+	return available
+}
+
+// validateBackendPathVariables Checks that every backend path variable can be resolved, it must
+// be called after the route is normalized
+func (r Route) validateBackendPathVariables() error {
+	available := r.Rewrite.availableVariables(r.PatternVariables())
+
+	for _, sourceVariable := range r.Rewrite.Variables {
+		if !available[sourceVariable] {
+			return fmt.Errorf("config 'rewrite.variables' source variable %s of route %s does not exist", sourceVariable, r.Name())
+		}
+	}
+
+	for _, variable := range pathVariables(r.BackendPath) {
+		if !available[variable.Name()] {
+			return fmt.Errorf("config 'route.backendPath' variable %s of route %s can not be resolved", variable, r.Name())
+		}
+	}
+
+	return nil
+}
+
+// BuildBackendPath Resolves the backend path of a request, following the route rewrite rules. The
+// query parameters moved into the path are removed from the returned query
+func (r Route) BuildBackendPath(
+	requestPath string,
+	pathValue func(string) string,
+	query url.Values,
+) (string, url.Values, error) {
+	values := make(map[string]string)
+
+	for _, variable := range r.PatternVariables() {
+		values[variable.Name()] = pathValue(variable.Name())
+	}
+
+	if r.Rewrite.compiledRegex != nil {
+		match := r.Rewrite.compiledRegex.FindStringSubmatch(requestPath)
+		if match == nil {
+			return "", nil, fmt.Errorf("%w: path %s does not match the route rewrite regex", ErrMissingRouteVariable, requestPath)
+		}
+
+		for i, name := range r.Rewrite.compiledRegex.SubexpNames() {
+			values[strconv.Itoa(i)] = match[i]
+			if name != "" {
+				values[name] = match[i]
+			}
+		}
+	}
+
+	if len(r.Rewrite.QueryToPath) > 0 {
+		query = cloneValues(query)
+
+		for queryParam, backendVariable := range r.Rewrite.QueryToPath {
+			values[backendVariable] = query.Get(queryParam)
+			query.Del(queryParam)
+		}
+	}
+
+	for backendVariable, sourceVariable := range r.Rewrite.Variables {
+		values[backendVariable] = values[sourceVariable]
+	}
+
+	backendPath := r.BackendPath
+	for _, variable := range pathVariables(r.BackendPath) {
+		value := values[variable.Name()]
+		if strings.TrimSpace(value) == "" && !variable.IsWildcard() {
+			return "", nil, fmt.Errorf("%w: %s", ErrMissingRouteVariable, variable.Name())
+		}
+
+		backendPath = variable.ReplaceFromPattern(backendPath, value)
+	}
+
+	if r.Rewrite.StripPrefix != "" {
+		backendPath = "/" + strings.TrimPrefix(strings.TrimPrefix(backendPath, r.Rewrite.StripPrefix), "/")
+	}
+
+	if r.Rewrite.AddPrefix != "" {
+		backendPath = strings.TrimSuffix(r.Rewrite.AddPrefix, "/") + "/" + strings.TrimPrefix(backendPath, "/")
+	}
+
+	return backendPath, query, nil
+}
+
+func cloneValues(values url.Values) url.Values {
+	cloned := make(url.Values, len(values))
+	for key, value := range values {
+		cloned[key] = append([]string(nil), value...)
+	}
+
+	return cloned
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func newTestRewriteRoute(t *testing.T, route Route) Route {
+	t.Helper()
+
+	if route.Method == "" {
+		route.Method = "GET"
+	}
+
+	if err := route.ValidateAndNormalize(); err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+
+	return route
+}
+
+func TestRouteBuildBackendPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		route       Route
+		requestPath string
+		pathValues  map[string]string
+		query       url.Values
+		wantPath    string
+		wantQuery   url.Values
+	}{
+		{
+			name:        "route variables",
+			route:       Route{GatekeeperPath: "/users/{id}", BackendPath: "/v1/users/{id}"},
+			requestPath: "/users/42",
+			pathValues:  map[string]string{"id": "42"},
+			wantPath:    "/v1/users/42",
+		},
+		{
+			name: "numbered and named regex groups",
+			route: Route{
+				GatekeeperPath: "/legacy/",
+				BackendPath:    "/v2/{tenant}/orders/{2}",
+				Rewrite:        Rewrite{Regex: `^/legacy/(?P<tenant>[a-z]+)/orders/([0-9]+)$`},
+			},
+			requestPath: "/legacy/acme/orders/7",
+			wantPath:    "/v2/acme/orders/7",
+		},
+		{
+			name: "aliases",
+			route: Route{
+				GatekeeperPath: "/users/{userId}/posts/{postId}",
+				BackendPath:    "/v1/posts/{id}/author/{author}",
+				Rewrite:        Rewrite{Variables: map[string]string{"id": "postId", "author": "userId"}},
+			},
+			requestPath: "/users/1/posts/2",
+			pathValues:  map[string]string{"userId": "1", "postId": "2"},
+			wantPath:    "/v1/posts/2/author/1",
+		},
+		{
+			name: "query to path",
+			route: Route{
+				GatekeeperPath: "/search",
+				BackendPath:    "/v1/search/{category}",
+				Rewrite:        Rewrite{QueryToPath: map[string]string{"cat": "category"}},
+			},
+			requestPath: "/search",
+			query:       url.Values{"cat": {"books"}, "q": {"go"}},
+			wantPath:    "/v1/search/books",
+			wantQuery:   url.Values{"q": {"go"}},
+		},
+		{
+			name: "query to path aliased",
+			route: Route{
+				GatekeeperPath: "/search",
+				BackendPath:    "/v1/search/{kind}",
+				Rewrite: Rewrite{
+					QueryToPath: map[string]string{"cat": "category"},
+					Variables:   map[string]string{"kind": "category"},
+				},
+			},
+			requestPath: "/search",
+			query:       url.Values{"cat": {"books"}},
+			wantPath:    "/v1/search/books",
+			wantQuery:   url.Values{},
+		},
+		{
+			name: "strip prefix",
+			route: Route{
+				GatekeeperPath: "/api/items",
+				BackendPath:    "/api/items",
+				Rewrite:        Rewrite{StripPrefix: "/api"},
+			},
+			requestPath: "/api/items",
+			wantPath:    "/items",
+		},
+		{
+			name: "strip the whole path",
+			route: Route{
+				GatekeeperPath: "/api",
+				BackendPath:    "/api",
+				Rewrite:        Rewrite{StripPrefix: "/api"},
+			},
+			requestPath: "/api",
+			wantPath:    "/",
+		},
+		{
+			name: "add prefix",
+			route: Route{
+				GatekeeperPath: "/items",
+				BackendPath:    "/items",
+				Rewrite:        Rewrite{AddPrefix: "/internal/v3/"},
+			},
+			requestPath: "/items",
+			wantPath:    "/internal/v3/items",
+		},
+		{
+			name: "strip and add prefix",
+			route: Route{
+				GatekeeperPath: "/public/items/{id}",
+				BackendPath:    "/public/items/{id}",
+				Rewrite:        Rewrite{StripPrefix: "/public", AddPrefix: "/private"},
+			},
+			requestPath: "/public/items/9",
+			pathValues:  map[string]string{"id": "9"},
+			wantPath:    "/private/items/9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := newTestRewriteRoute(t, test.route)
+			query := test.query
+			if query == nil {
+				query = url.Values{}
+			}
+			originalQuery := cloneValues(query)
+
+			backendPath, gotQuery, err := route.BuildBackendPath(test.requestPath, func(name string) string {
+				return test.pathValues[name]
+			}, query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if backendPath != test.wantPath {
+				t.Errorf("expected path %q, got %q", test.wantPath, backendPath)
+			}
+
+			if test.wantQuery != nil && gotQuery.Encode() != test.wantQuery.Encode() {
+				t.Errorf("expected query %q, got %q", test.wantQuery.Encode(), gotQuery.Encode())
+			}
+
+			if query.Encode() != originalQuery.Encode() {
+				t.Errorf("expected the request query to be left untouched, got %q", query.Encode())
+			}
+		})
+	}
+}
+
+func TestRouteBuildBackendPathMissingVariables(t *testing.T) {
+	regexRoute := newTestRewriteRoute(t, Route{
+		GatekeeperPath: "/legacy/",
+		BackendPath:    "/v2/{1}",
+		Rewrite:        Rewrite{Regex: `^/legacy/([0-9]+)$`},
+	})
+	if _, _, err := regexRoute.BuildBackendPath("/legacy/abc", func(string) string { return "" }, url.Values{}); !errors.Is(err, ErrMissingRouteVariable) {
+		t.Errorf("expected a missing variable error for an unmatched regex, got %v", err)
+	}
+
+	queryRoute := newTestRewriteRoute(t, Route{
+		GatekeeperPath: "/search",
+		BackendPath:    "/v1/search/{category}",
+		Rewrite:        Rewrite{QueryToPath: map[string]string{"cat": "category"}},
+	})
+	if _, _, err := queryRoute.BuildBackendPath("/search", func(string) string { return "" }, url.Values{}); !errors.Is(err, ErrMissingRouteVariable) {
+		t.Errorf("expected a missing variable error for a missing query parameter, got %v", err)
+	}
+}
+
+func TestRewriteValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr bool
+	}{
+		{
+			name:  "alias of a route variable",
+			route: Route{GatekeeperPath: "/a/{x}", BackendPath: "/b/{y}", Rewrite: Rewrite{Variables: map[string]string{"y": "x"}}},
+		},
+		{
+			name:    "chained aliases",
+			route:   Route{GatekeeperPath: "/a/{x}", BackendPath: "/b/{z}", Rewrite: Rewrite{Variables: map[string]string{"y": "x", "z": "y"}}},
+			wantErr: true,
+		},
+		{
+			name:    "self alias",
+			route:   Route{GatekeeperPath: "/a/{x}", BackendPath: "/b/{x}", Rewrite: Rewrite{Variables: map[string]string{"x": "x"}}},
+			wantErr: true,
+		},
+		{
+			name:    "alias of an unknown variable",
+			route:   Route{GatekeeperPath: "/a/{x}", BackendPath: "/b/{y}", Rewrite: Rewrite{Variables: map[string]string{"y": "missing"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unresolved backend variable",
+			route:   Route{GatekeeperPath: "/a", BackendPath: "/b/{y}"},
+			wantErr: true,
+		},
+		{
+			name:    "two query parameters to the same variable",
+			route:   Route{GatekeeperPath: "/a", BackendPath: "/b/{y}", Rewrite: Rewrite{QueryToPath: map[string]string{"p": "y", "q": "y"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			route:   Route{GatekeeperPath: "/a", BackendPath: "/b", Rewrite: Rewrite{Regex: "(["}},
+			wantErr: true,
+		},
+		{
+			name:    "relative strip prefix",
+			route:   Route{GatekeeperPath: "/a", BackendPath: "/b", Rewrite: Rewrite{StripPrefix: "a"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.route.Method = "GET"
+			if err := test.route.ValidateAndNormalize(); (err != nil) != test.wantErr {
+				t.Errorf("expected error=%t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -9,26 +9,46 @@ import (
 )
 
 type Route struct {
-	Method         string            `yaml:"method"`
-	BackendPath    string            `yaml:"backendPath"`
-	GatekeeperPath string            `yaml:"gatekeeperPath"`
-	TimeoutSeconds int               `yaml:"timeoutSeconds"`
-	IsPublic       bool              `yaml:"isPublic"`
-	PassHeaders    bool              `yaml:"passHeaders"`
-	Scopes         []string          `yaml:"scopes"`
-	Headers        map[string]string `yaml:"headers"`
-	HandlerFunc    http.HandlerFunc
+	Type                RouteType         `yaml:"type"`
+	Method              string            `yaml:"method"`
+	BackendPath         string            `yaml:"backendPath"`
+	GatekeeperPath      string            `yaml:"gatekeeperPath"`
+	TimeoutSeconds      int               `yaml:"timeoutSeconds"`
+	IsPublic            bool              `yaml:"isPublic"`
+	PassHeaders         bool              `yaml:"passHeaders"`
+	Scopes              []string          `yaml:"scopes"`
+	Headers             map[string]string `yaml:"headers"`
+	ResponseHeaders     ResponseHeaders   `yaml:"responseHeaders"`
+	RequestHeaderRules  HeaderRules       `yaml:"requestHeaderRules"`
+	ResponseHeaderRules HeaderRules       `yaml:"responseHeaderRules"`
+	CircuitBreaker      CircuitBreaker    `yaml:"circuitBreaker"`
+	Retry               Retry             `yaml:"retry"`
+	Upgrade             Upgrade           `yaml:"upgrade"`
+	Streaming           Streaming         `yaml:"streaming"`
+	Rewrite             Rewrite           `yaml:"rewrite"`
+	RateLimits          RateLimits        `yaml:"rateLimits"`
+	Quotas              []string          `yaml:"quotas"`
+	IPAllow             []string          `yaml:"ipAllow"`
+	IPDeny              []string          `yaml:"ipDeny"`
+	CORS                CORS              `yaml:"cors"`
+	HandlerFunc         http.HandlerFunc
+	ipFilter            IPFilter
 }
 
 func (r Route) Name() string {
-	routeName := strings.ToLower(fmt.Sprintf("%s-%s", r.Method, strings.ReplaceAll(r.GatekeeperPath, "/", "-")))
+	method := r.Method
+	if r.IsAnyMethod() {
+		method = "any"
+	}
+
+	routeName := strings.ToLower(fmt.Sprintf("%s-%s", method, strings.ReplaceAll(r.GatekeeperPath, "/", "-")))
 	routeName = strings.ReplaceAll(routeName, "--", "-")
 
 	return routeName
 }
 
 func (r Route) Validate() error {
-	if strings.TrimSpace(r.Method) == "" {
+	if strings.TrimSpace(r.Method) == "" && r.Type != RouteTypeGRPC {
 		return errors.New("config 'route.method' must be present and not be empty")
 	}
 
@@ -40,12 +60,77 @@ func (r Route) Validate() error {
 		return errors.New("config 'route.gatekeeperPath' should not start with /api-gatekeeper, this is a reserved route namespace")
 	}
 
+	if err := r.ResponseHeaders.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.RequestHeaderRules.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.ResponseHeaderRules.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.CircuitBreaker.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.Upgrade.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.Streaming.Validate(); err != nil {
+		return err
+	}
+
+	if r.Upgrade.Enabled && r.Streaming.Enabled {
+		return errors.New("config 'route.upgrade' and 'route.streaming' must not be enabled at the same time")
+	}
+
+	if err := r.Type.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.Rewrite.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.RateLimits.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.CORS.Validate(); err != nil {
+		return err
+	}
+
+	if err := validateIPFilter("route", r.IPAllow, r.IPDeny); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (r *Route) Normalize() {
 	r.Method = strings.ToUpper(r.Method)
 
+	if r.Type == "" {
+		r.Type = RouteTypeHTTP
+	}
+
+	if r.Type == RouteTypeGRPC {
+		// gRPC metadata and content negotiation travel as headers, so they must always be passed
+		r.PassHeaders = true
+
+		if r.Method == "" {
+			r.Method = http.MethodPost
+		}
+	}
+
 	if r.Scopes == nil {
 		r.Scopes = make([]string, 0)
 	}
@@ -57,6 +142,18 @@ func (r *Route) Normalize() {
 	if len(r.GatekeeperPath) == 0 {
 		r.GatekeeperPath = r.BackendPath
 	}
+
+	r.ResponseHeaders.Normalize()
+	r.RequestHeaderRules.Normalize()
+	r.ResponseHeaderRules.Normalize()
+	r.CircuitBreaker.Normalize()
+	r.Retry.Normalize()
+	r.Upgrade.Normalize()
+	r.Streaming.Normalize()
+	r.Rewrite.Normalize()
+	r.RateLimits.Normalize()
+	r.CORS.Normalize()
+	r.ipFilter = newIPFilter(r.IPAllow, r.IPDeny)
 }
 
 func (r *Route) ValidateAndNormalize() error {
@@ -66,26 +163,53 @@ func (r *Route) ValidateAndNormalize() error {
 
 	r.Normalize()
 
-	return nil
+	return r.validateBackendPathVariables()
 }
 
 func (r *Route) Pattern() string {
+	if r.IsAnyMethod() {
+		return r.GatekeeperPath
+	}
+
 	return fmt.Sprintf("%s %s", strings.ToUpper(r.Method), r.GatekeeperPath)
 }
 
+// IsAnyMethod Reports if the route accepts every method, like the backend mount catch-all route
+func (r Route) IsAnyMethod() bool {
+	return r.Method == ""
+}
+
 var patternVariabelesRegex = regexp.MustCompile(`\{(.*?)\}`)
 
 func (r *Route) PatternVariables() []RouteVariable {
-	variables := patternVariabelesRegex.FindAllString(r.GatekeeperPath, -1)
-	routeVairables := make([]RouteVariable, len(variables))
+	return pathVariables(r.GatekeeperPath)
+}
+
+// pathVariables Returns the variables of a path, ignoring the {$} end of path marker
+func pathVariables(path string) []RouteVariable {
+	variables := patternVariabelesRegex.FindAllString(path, -1)
+	routeVairables := make([]RouteVariable, 0, len(variables))
 
 	for i := range variables {
-		routeVairables[i] = NewRouteVariable(variables[i])
+		if variables[i] == "{$}" {
+			continue
+		}
+
+		routeVairables = append(routeVairables, NewRouteVariable(variables[i]))
 	}
 
 	return routeVairables
 }
 
+// IPFilter Returns the filter of this route only, it is checked alongside the API and backend ones
+func (r Route) IPFilter() IPFilter {
+	return r.ipFilter
+}
+
+func (r *Route) IsGRPC() bool {
+	return r.Type == RouteTypeGRPC
+}
+
 func (r *Route) IsApplicationRoute() bool {
 	return r.HandlerFunc != nil
 }
@@ -97,15 +221,16 @@ func NewRouteVariable(routeVariable string) RouteVariable {
 }
 
 func (r RouteVariable) Name() string {
-	replacer := strings.NewReplacer("{", "", "}", "")
+	replacer := strings.NewReplacer("{", "", "}", "", "...", "")
 
 	return replacer.Replace(string(r))
 }
 
-func (r RouteVariable) ReplaceFromPattern(url string, value string) string {
-	if !strings.Contains(url, string(r)) || strings.TrimSpace(value) == "" {
-		return url
-	}
+// IsWildcard Reports if the variable matches all the remaining path segments, like {path...}
+func (r RouteVariable) IsWildcard() bool {
+	return strings.HasSuffix(string(r), "...}")
+}
 
+func (r RouteVariable) ReplaceFromPattern(url string, value string) string {
 	return strings.ReplaceAll(url, string(r), value)
 }
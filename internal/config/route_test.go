@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestRouteValidateUpgradeAndStreaming(t *testing.T) {
+	tests := []struct {
+		name      string
+		upgrade   bool
+		streaming bool
+		wantErr   bool
+	}{
+		{"none", false, false, false},
+		{"upgrade", true, false, false},
+		{"streaming", false, true, false},
+		{"both", true, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := Route{
+				Method:         "GET",
+				GatekeeperPath: "/events",
+				BackendPath:    "/events",
+				Upgrade:        Upgrade{Enabled: test.upgrade},
+				Streaming:      Streaming{Enabled: test.streaming},
+			}
+
+			if err := route.ValidateAndNormalize(); (err != nil) != test.wantErr {
+				t.Errorf("expected error=%t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
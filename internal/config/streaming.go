@@ -0,0 +1,31 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultStreamingIdleTimeoutSeconds = 60
+
+type Streaming struct {
+	Enabled            bool `yaml:"enabled"`
+	IdleTimeoutSeconds int  `yaml:"idleTimeoutSeconds"`
+}
+
+func (s Streaming) Validate() error {
+	if s.IdleTimeoutSeconds < 0 {
+		return errors.New("config 'streaming.idleTimeoutSeconds' must not be negative")
+	}
+
+	return nil
+}
+
+func (s *Streaming) Normalize() {
+	if s.Enabled && s.IdleTimeoutSeconds == 0 {
+		s.IdleTimeoutSeconds = defaultStreamingIdleTimeoutSeconds
+	}
+}
+
+func (s Streaming) IdleTimeout() time.Duration {
+	return time.Duration(s.IdleTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultTransportMaxIdleConns        = 100
+	defaultTransportMaxIdleConnsPerHost = 16
+	defaultTransportIdleConnTimeout     = 90
+	defaultTransportDialTimeout         = 30
+	defaultTransportTLSHandshakeTimeout = 10
+	defaultTransportKeepAliveSeconds    = 30
+)
+
+type Transport struct {
+	MaxIdleConns                 int `yaml:"maxIdleConns"`
+	MaxIdleConnsPerHost          int `yaml:"maxIdleConnsPerHost"`
+	MaxConnsPerHost              int `yaml:"maxConnsPerHost"`
+	IdleConnTimeoutSeconds       int `yaml:"idleConnTimeoutSeconds"`
+	DialTimeoutSeconds           int `yaml:"dialTimeoutSeconds"`
+	KeepAliveSeconds             int `yaml:"keepAliveSeconds"`
+	TLSHandshakeTimeoutSeconds   int `yaml:"tlsHandshakeTimeoutSeconds"`
+	ResponseHeaderTimeoutSeconds int `yaml:"responseHeaderTimeoutSeconds"`
+}
+
+func (t Transport) Validate() error {
+	if t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 {
+		return errors.New("config 'transport' connection limits must not be negative")
+	}
+
+	if t.IdleConnTimeoutSeconds < 0 ||
+		t.DialTimeoutSeconds < 0 ||
+		t.KeepAliveSeconds < 0 ||
+		t.TLSHandshakeTimeoutSeconds < 0 ||
+		t.ResponseHeaderTimeoutSeconds < 0 {
+		return errors.New("config 'transport' timeouts must not be negative")
+	}
+
+	return nil
+}
+
+func (t *Transport) Normalize() {
+	if t.MaxIdleConns == 0 {
+		t.MaxIdleConns = defaultTransportMaxIdleConns
+	}
+
+	if t.MaxIdleConnsPerHost == 0 {
+		t.MaxIdleConnsPerHost = defaultTransportMaxIdleConnsPerHost
+	}
+
+	if t.IdleConnTimeoutSeconds == 0 {
+		t.IdleConnTimeoutSeconds = defaultTransportIdleConnTimeout
+	}
+
+	if t.DialTimeoutSeconds == 0 {
+		t.DialTimeoutSeconds = defaultTransportDialTimeout
+	}
+
+	if t.KeepAliveSeconds == 0 {
+		t.KeepAliveSeconds = defaultTransportKeepAliveSeconds
+	}
+
+	if t.TLSHandshakeTimeoutSeconds == 0 {
+		t.TLSHandshakeTimeoutSeconds = defaultTransportTLSHandshakeTimeout
+	}
+}
+
+func (t Transport) IdleConnTimeout() time.Duration {
+	return time.Duration(t.IdleConnTimeoutSeconds) * time.Second
+}
+
+func (t Transport) DialTimeout() time.Duration {
+	return time.Duration(t.DialTimeoutSeconds) * time.Second
+}
+
+func (t Transport) KeepAlive() time.Duration {
+	return time.Duration(t.KeepAliveSeconds) * time.Second
+}
+
+func (t Transport) TLSHandshakeTimeout() time.Duration {
+	return time.Duration(t.TLSHandshakeTimeoutSeconds) * time.Second
+}
+
+func (t Transport) ResponseHeaderTimeout() time.Duration {
+	return time.Duration(t.ResponseHeaderTimeoutSeconds) * time.Second
+}
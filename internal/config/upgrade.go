@@ -0,0 +1,31 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultUpgradeIdleTimeoutSeconds = 300
+
+type Upgrade struct {
+	Enabled            bool `yaml:"enabled"`
+	IdleTimeoutSeconds int  `yaml:"idleTimeoutSeconds"`
+}
+
+func (u Upgrade) Validate() error {
+	if u.IdleTimeoutSeconds < 0 {
+		return errors.New("config 'upgrade.idleTimeoutSeconds' must not be negative")
+	}
+
+	return nil
+}
+
+func (u *Upgrade) Normalize() {
+	if u.Enabled && u.IdleTimeoutSeconds == 0 {
+		u.IdleTimeoutSeconds = defaultUpgradeIdleTimeoutSeconds
+	}
+}
+
+func (u Upgrade) IdleTimeout() time.Duration {
+	return time.Duration(u.IdleTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type BackendHealth struct {
+	Name          string         `json:"name,omitempty"`
+	Healthy       bool           `json:"healthy"`
+	CircuitState  string         `json:"circuit_state,omitempty"`
+	ActiveTunnels int64          `json:"active_tunnels"`
+	Targets       []TargetHealth `json:"targets,omitempty"`
+}
+
+type TargetHealth struct {
+	Host              string     `json:"host,omitempty"`
+	Healthy           bool       `json:"healthy"`
+	ActiveConnections int64      `json:"active_connections"`
+	LastCheckedAt     *time.Time `json:"last_checked_at,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+}
+
+type BackendRequestParams struct {
+	User             User
+	RequestID        string
+	ClientIP         string
+	ForwardedHeaders http.Header
+	Body             io.ReadCloser
+	ContentLength    int64
+	Trailer          http.Header
+	Headers          http.Header
+	QueryParams      url.Values
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type QuotaUsage struct {
+	Name      string    `json:"name"`
+	Period    string    `json:"period"`
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type RateLimitStatus struct {
+	Name      string
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Window    time.Duration
+	Reset     time.Duration
+}
@@ -35,5 +35,6 @@ func InitializeDatabase(
 		&gatekeeperUser{},
 		&gatekeeperUserProperty{},
 		&gatekeeperUserScope{},
+		&gatekeeperLimiterCounter{},
 	)
 }
@@ -0,0 +1,110 @@
+package gorm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// limiterCleanupInterval Is how often the expired counters are deleted from the database
+const limiterCleanupInterval = time.Minute
+
+// incrementLimiterCounterQuery Upserts the counter in a single statement, so concurrent increments
+// from every gatekeeper instance are atomic. Expired counters are restarted from the delta
+const incrementLimiterCounterQuery = `
+INSERT INTO gatekeeper_limiter_counters (counter_key, value, expires_at)
+VALUES (?, ?, ?)
+ON CONFLICT (counter_key) DO UPDATE SET
+	value = CASE
+		WHEN gatekeeper_limiter_counters.expires_at <= ? THEN excluded.value
+		ELSE gatekeeper_limiter_counters.value + excluded.value
+	END,
+	expires_at = CASE
+		WHEN gatekeeper_limiter_counters.expires_at <= ? THEN excluded.expires_at
+		ELSE gatekeeper_limiter_counters.expires_at
+	END
+RETURNING value`
+
+// Limiter Is a limiter store that keeps the counters in the database, so the limits are shared by
+// every gatekeeper instance connected to it. The expiration times are stored as unix milliseconds
+type Limiter struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewLimiter(db *gorm.DB) *Limiter {
+	return &Limiter{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Public methods
+func (l *Limiter) Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	now := l.now().UnixMilli()
+
+	var value int64
+	result := l.db.WithContext(ctx).
+		Raw(incrementLimiterCounterQuery, key, delta, expiresAt.UnixMilli(), now, now).
+		Scan(&value)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return value, nil
+}
+
+func (l *Limiter) Get(ctx context.Context, key string) (int64, error) {
+	var counters []gatekeeperLimiterCounter
+	result := l.db.WithContext(ctx).
+		Where("counter_key = ? AND expires_at > ?", key, l.now().UnixMilli()).
+		Limit(1).
+		Find(&counters)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if len(counters) == 0 {
+		return 0, nil
+	}
+
+	return counters[0].Value, nil
+}
+
+func (l *Limiter) Delete(ctx context.Context, key string) error {
+	result := l.db.WithContext(ctx).
+		Where("counter_key = ?", key).
+		Delete(&gatekeeperLimiterCounter{})
+
+	return result.Error
+}
+
+// StartCleanup Deletes the expired counters in background until the context is done
+func (l *Limiter) StartCleanup(ctx context.Context, logger *slog.Logger) {
+	go func() {
+		ticker := time.NewTicker(limiterCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.deleteExpired(ctx); err != nil && ctx.Err() == nil {
+					logger.Error("Failed to delete expired limiter counters", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Private methods
+func (l *Limiter) deleteExpired(ctx context.Context) error {
+	result := l.db.WithContext(ctx).
+		Where("expires_at <= ?", l.now().UnixMilli()).
+		Delete(&gatekeeperLimiterCounter{})
+
+	return result.Error
+}
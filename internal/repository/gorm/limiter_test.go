@@ -0,0 +1,181 @@
+package gorm
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+var limiterTestNow = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestLimiter(t *testing.T) (*Limiter, *time.Time) {
+	t.Helper()
+
+	db, err := OpenDatabaseConnection(config.Database{
+		Provider: config.DatabaseProviderSqlite,
+		DSN:      filepath.Join(t.TempDir(), "limiter.db"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := InitializeDatabase(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := limiterTestNow
+	limiter := NewLimiter(db)
+	limiter.now = func() time.Time { return now }
+
+	return limiter, &now
+}
+
+func TestLimiterIncrement(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+	expiresAt := now.Add(time.Minute)
+
+	for i, want := range []int64{1, 3, 6} {
+		value, err := limiter.Increment(ctx, "user:1", int64(i+1), expiresAt)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if value != want {
+			t.Errorf("expected the counter to be %d, got %d", want, value)
+		}
+	}
+
+	// A later expiration does not extend a live counter
+	if _, err := limiter.Increment(ctx, "user:1", 1, now.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	*now = expiresAt.Add(-time.Millisecond)
+	if value, _ := limiter.Get(ctx, "user:1"); value != 7 {
+		t.Errorf("expected the counter to still be live, got %d", value)
+	}
+
+	*now = expiresAt
+	if value, _ := limiter.Get(ctx, "user:1"); value != 0 {
+		t.Errorf("expected the counter to be expired, got %d", value)
+	}
+
+	value, err := limiter.Increment(ctx, "user:1", 2, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if value != 2 {
+		t.Errorf("expected the expired counter to restart from the delta, got %d", value)
+	}
+
+	*now = now.Add(59 * time.Second)
+	if value, _ := limiter.Get(ctx, "user:1"); value != 2 {
+		t.Errorf("expected the restarted counter to use the new expiration, got %d", value)
+	}
+}
+
+func TestLimiterNegativeIncrement(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+
+	limiter.Increment(ctx, "user:1", 5, now.Add(time.Minute))
+
+	value, err := limiter.Increment(ctx, "user:1", -2, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if value != 3 {
+		t.Errorf("expected the counter to be rolled back to 3, got %d", value)
+	}
+}
+
+func TestLimiterGetAndDelete(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+
+	if value, err := limiter.Get(ctx, "missing"); err != nil || value != 0 {
+		t.Errorf("expected a missing counter to be 0, got %d, %v", value, err)
+	}
+
+	limiter.Increment(ctx, "user:1", 4, now.Add(time.Minute))
+	limiter.Increment(ctx, "user:2", 1, now.Add(time.Minute))
+
+	if value, err := limiter.Get(ctx, "user:1"); err != nil || value != 4 {
+		t.Errorf("expected the counter to be 4, got %d, %v", value, err)
+	}
+
+	if err := limiter.Delete(ctx, "user:1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if value, _ := limiter.Get(ctx, "user:1"); value != 0 {
+		t.Errorf("expected the deleted counter to be 0, got %d", value)
+	}
+
+	if value, _ := limiter.Get(ctx, "user:2"); value != 1 {
+		t.Errorf("expected the other counters to be kept, got %d", value)
+	}
+
+	if err := limiter.Delete(ctx, "missing"); err != nil {
+		t.Errorf("expected deleting a missing counter to succeed, got %v", err)
+	}
+}
+
+func TestLimiterDeleteExpired(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+
+	limiter.Increment(ctx, "expired", 1, now.Add(time.Second))
+	limiter.Increment(ctx, "live", 1, now.Add(time.Hour))
+
+	*now = now.Add(time.Second)
+	if err := limiter.deleteExpired(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keys []string
+	if err := limiter.db.Model(&gatekeeperLimiterCounter{}).Pluck("counter_key", &keys).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "live" {
+		t.Errorf("expected only the live counter to be kept, got %v", keys)
+	}
+}
+
+func TestLimiterConcurrentIncrements(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+	expiresAt := now.Add(time.Minute)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := limiter.Increment(ctx, "user:1", 1, expiresAt); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := limiter.Get(ctx, "user:1"); value != 50 {
+		t.Errorf("expected every increment to be counted, got %d", value)
+	}
+}
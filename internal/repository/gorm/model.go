@@ -50,3 +50,9 @@ func (u *gatekeeperUserScope) BeforeSave(tx *gorm.DB) error {
 	u.ID = uuidutil.NewWhenEmptyOrInvalid(u.ID)
 	return nil
 }
+
+type gatekeeperLimiterCounter struct {
+	CounterKey string `gorm:"primaryKey"`
+	Value      int64
+	ExpiresAt  int64 `gorm:"index:idx_gatekeeper_limiter_counters_expires_at"`
+}
@@ -2,8 +2,10 @@ package gorm
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/gustapinto/api-gatekeeper/internal/model"
+	"github.com/gustapinto/api-gatekeeper/internal/service"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -27,7 +29,7 @@ func (u *User) Create(params model.CreateUserParams) (*model.User, error) {
 	gUser := u.makeGatekeeperUserFromCreateUserParams(params)
 	result := u.db.Create(gUser)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, u.translateError(result.Error)
 	}
 
 	return u.GetByID(gUser.ID)
@@ -36,7 +38,11 @@ func (u *User) Create(params model.CreateUserParams) (*model.User, error) {
 func (u *User) Delete(userID string) error {
 	result := u.db.Delete(&gatekeeperUser{}, "id = ?", userID)
 	if result.Error != nil {
-		return result.Error
+		return u.translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return u.translateError(gorm.ErrRecordNotFound)
 	}
 
 	return nil
@@ -61,7 +67,7 @@ func (u *User) GetByID(userID string) (*model.User, error) {
 	var gUser gatekeeperUser
 	result := u.db.Preload(preloadClause).First(&gUser, "id = ?", userID)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, u.translateError(result.Error)
 	}
 
 	return u.makeUserFromGatekeeperUser(gUser), nil
@@ -71,7 +77,7 @@ func (u *User) GetByLogin(userLogin string) (*model.User, error) {
 	var gUser gatekeeperUser
 	result := u.db.Preload(preloadClause).First(&gUser, "login = ?", userLogin)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, u.translateError(result.Error)
 	}
 
 	return u.makeUserFromGatekeeperUser(gUser), nil
@@ -99,21 +105,26 @@ func (u *User) Update(params model.UpdateUserParams) (*model.User, error) {
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, u.translateError(err)
 	}
 
 	return u.GetByID(gUser.ID)
 }
 
-func (*User) IsAlreadyExistsError(err error) bool {
-	if err == nil {
-		return false
+// Private methods
+
+// translateError Translates the gorm errors into the service domain errors
+func (*User) translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("user %w", service.ErrNotFound)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("user %w", service.ErrConflict)
 	}
 
-	return errors.Is(err, gorm.ErrDuplicatedKey)
+	return err
 }
 
-// Private methods
 func (*User) makeGatekeeperUserFromCreateUserParams(params model.CreateUserParams) *gatekeeperUser {
 	var properties []gatekeeperUserProperty
 	for property, value := range params.Properties {
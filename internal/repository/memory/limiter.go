@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiterCleanupInterval Is how often the expired counters are removed from memory
+const limiterCleanupInterval = time.Minute
+
+type limiterCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// Limiter Is a limiter store that keeps the counters in the process memory, so each gatekeeper
+// instance enforces the limits on its own
+type Limiter struct {
+	mu          sync.Mutex
+	counters    map[string]limiterCounter
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		counters:    make(map[string]limiterCounter),
+		lastCleanup: time.Now(),
+		now:         time.Now,
+	}
+}
+
+func (l *Limiter) Increment(_ context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastCleanup) > limiterCleanupInterval {
+		for counterKey, counter := range l.counters {
+			if !now.Before(counter.expiresAt) {
+				delete(l.counters, counterKey)
+			}
+		}
+
+		l.lastCleanup = now
+	}
+
+	counter, exists := l.counters[key]
+	if !exists || !now.Before(counter.expiresAt) {
+		counter = limiterCounter{expiresAt: expiresAt}
+	}
+
+	counter.value += delta
+	l.counters[key] = counter
+
+	return counter.value, nil
+}
+
+func (l *Limiter) Get(_ context.Context, key string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter, exists := l.counters[key]
+	if !exists || !l.now().Before(counter.expiresAt) {
+		return 0, nil
+	}
+
+	return counter.value, nil
+}
+
+func (l *Limiter) Delete(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.counters, key)
+
+	return nil
+}
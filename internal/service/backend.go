@@ -1,47 +1,253 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
 )
 
-type Backend struct{}
+var ErrNoAvailableUpstream = errors.New("no available upstream target")
 
-func NewBackend() Backend {
-	return Backend{}
+type Backend struct {
+	upstreams         *upstreamPool
+	identityAssertion *IdentityAssertion
+	requestIDHeader   string
 }
 
-func (Backend) mergeHeaders(headerMaps ...map[string]string) map[string]string {
-	headers := make(map[string]string)
+// NewBackend Creates the backend service, the identityAssertion may be nil if the backends
+// should not receive signed identity assertions. The request ID is forwarded in the
+// requestIDHeader and in the X-Api-Gatekeeper-Request headers
+func NewBackend(backends []config.Backend, identityAssertion *IdentityAssertion, requestIDHeader string) *Backend {
+	return &Backend{
+		upstreams:         newUpstreamPool(backends),
+		identityAssertion: identityAssertion,
+		requestIDHeader:   requestIDHeader,
+	}
+}
+
+// StartHealthChecks Starts probing the backends upstream targets in background, targets that
+// fail their health checks are taken out of rotation until they recover
+func (b *Backend) StartHealthChecks(ctx context.Context, logger *slog.Logger) {
+	b.upstreams.startHealthChecks(ctx, logger)
+}
+
+func (b *Backend) Health() []model.BackendHealth {
+	return b.upstreams.health()
+}
+
+func (b *Backend) JWKS() model.JSONWebKeySet {
+	return b.identityAssertion.JWKS()
+}
+
+// Close Closes every idle upstream connection, it should be called on application exit
+func (b *Backend) Close() {
+	b.upstreams.close()
+}
+
+func (*Backend) hashKey(backend config.Backend, userId string, requestHeaders http.Header) string {
+	switch backend.LoadBalancing.HashOn {
+	case config.HashOnUser:
+		return userId
+	case config.HashOnHeader:
+		return requestHeaders.Get(backend.LoadBalancing.HashHeader)
+	}
+
+	return ""
+}
+
+// addUpgradeHeaders Adds the protocol upgrade handshake headers, they are always forwarded as the
+// upgrade can not be done without them
+func (*Backend) addUpgradeHeaders(headers http.Header, requestHeaders http.Header) {
+	headers.Set("Connection", "Upgrade")
+	headers.Set("Upgrade", requestHeaders.Get("Upgrade"))
+
+	for key, values := range requestHeaders {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), "Sec-Websocket-") {
+			headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+}
+
+func (*Backend) mergeHeaders(requestHeaders http.Header, headerMaps ...map[string]string) http.Header {
+	headers := make(http.Header)
 
 	for _, headerMap := range headerMaps {
 		for key, value := range headerMap {
-			headers[key] = value
+			headers.Set(key, value)
 		}
 	}
 
+	for key, values := range requestHeaders {
+		headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+
 	return headers
 }
 
-func (b Backend) DoRequestToBackendRoute(
-	userId string,
-	requestId string,
+func (b *Backend) DoRequestToBackendRoute(
+	ctx context.Context,
 	backend config.Backend,
 	route config.Route,
+	params model.BackendRequestParams,
+) (*http.Response, error) {
+	requestHeaders := params.Headers
+	upstream := b.upstreams.get(backend)
+	policy := newRetryPolicy(route.RetryPolicy(backend), route.Method)
+	isUpgrade := route.Upgrade.Enabled && httputil.IsUpgradeRequest(requestHeaders)
+
+	// The route timeout bounds the whole exchange, including every retry attempt and the
+	// response body read. Upgraded connections and streaming routes are bounded by their idle
+	// timeouts instead
+	requestCtx, cancel := ctx, context.CancelFunc(func() {})
+	var idleTimeout *idleTimeout
+	switch {
+	case route.Streaming.Enabled:
+		requestCtx, cancel = context.WithCancel(ctx)
+		idleTimeout = newIdleTimeout(route.Streaming.IdleTimeout(), cancel)
+	case route.TimeoutSeconds > 0 && !isUpgrade:
+		requestCtx, cancel = context.WithTimeout(ctx, time.Duration(route.TimeoutSeconds)*time.Second)
+	}
+
+	requestBody := &replayableBody{rest: params.Body}
+	if isUpgrade {
+		policy.maxAttempts = 1
+	}
+
+	if policy.isEnabled() {
+		var err error
+		if requestBody, err = newReplayableBody(params.Body, policy.cfg.BodyBufferBytes); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		if !requestBody.replayable {
+			policy.maxAttempts = 1
+		}
+	}
+
+	var additionalHeaders http.Header
+	if backend.PassHeaders || route.PassHeaders {
+		additionalHeaders = requestHeaders.Clone()
+		httputil.RemoveHopByHopHeaders(additionalHeaders)
+	}
+
+	headers := b.mergeHeaders(additionalHeaders, backend.Headers, route.Headers)
+
+	templateData := config.HeaderTemplateData{
+		User:      params.User,
+		RequestID: params.RequestID,
+		ClientIP:  params.ClientIP,
+	}
+
+	for _, rules := range []config.HeaderRules{backend.RequestHeaderRules, route.RequestHeaderRules} {
+		if err := rules.Apply(headers, requestHeaders, templateData); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	headers.Set("X-Api-Gatekeeper-User", params.User.ID)
+	headers.Set("X-Api-Gatekeeper-Request", params.RequestID)
+
+	if b.requestIDHeader != "" {
+		headers.Set(b.requestIDHeader, params.RequestID)
+	}
+
+	// The forwarding headers are always replaced, so clients can not spoof them
+	for _, key := range []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"} {
+		headers.Del(key)
+	}
+
+	for key, values := range params.ForwardedHeaders {
+		headers[key] = append([]string(nil), values...)
+	}
+
+	if err := b.identityAssertion.apply(headers, backend, route, params); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if isUpgrade {
+		b.addUpgradeHeaders(headers, requestHeaders)
+	}
+
+	// "Te: trailers" is hop-by-hop, but it must reach the backend for trailers, and gRPC, to work
+	if httputil.AcceptsTrailers(requestHeaders) {
+		headers.Set("Te", "trailers")
+	}
+
+	hashKey := b.hashKey(backend, params.User.ID, requestHeaders)
+
+	for attempt := 1; ; attempt++ {
+		response, err := b.doAttempt(
+			ctx,
+			requestCtx,
+			upstream,
+			route,
+			hashKey,
+			requestBody.reader(),
+			requestBody.contentLength(params.ContentLength),
+			params.Trailer,
+			headers,
+			params.QueryParams)
+
+		if !policy.shouldRetry(attempt, response, err) || !policy.wait(requestCtx, attempt) {
+			if err != nil {
+				// Classified before the cancel, so route timeouts are not mistaken for cancellations
+				upstreamErr := newUpstreamError(ctx, requestCtx, err)
+				idleTimeout.stop()
+				cancel()
+				return nil, upstreamErr
+			}
+
+			if idleTimeout != nil {
+				response.Body = idleTimeout.wrap(response.Body)
+			}
+
+			response.Body = wrapBodyOnClose(response.Body, func() {
+				idleTimeout.stop()
+				cancel()
+			})
+
+			return response, nil
+		}
+
+		if response != nil {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+	}
+}
+
+// doAttempt Sends the request to one of the backend available targets, the clientCtx is the
+// context of the original client request and is used to tell client aborts from upstream failures
+func (b *Backend) doAttempt(
+	clientCtx context.Context,
+	requestCtx context.Context,
+	upstream *backendUpstream,
+	route config.Route,
+	hashKey string,
 	body io.ReadCloser,
-	requestHeaders map[string]string,
-	queryParams map[string]string,
+	contentLength int64,
+	trailer http.Header,
+	headers http.Header,
+	queryParams url.Values,
 ) (*http.Response, error) {
-	client := http.Client{
-		Timeout: time.Duration(route.TimeoutSeconds) * time.Second,
+	target := upstream.balancer.pick(hashKey)
+	if target == nil {
+		return nil, ErrNoAvailableUpstream
 	}
-	defer client.CloseIdleConnections()
 
-	backendPath, err := url.JoinPath(backend.Host, route.BackendPath)
+	backendPath, err := url.JoinPath(target.host, route.BackendPath)
 	if err != nil {
 		return nil, err
 	}
@@ -52,30 +258,55 @@ func (b Backend) DoRequestToBackendRoute(
 	}
 
 	backendUrlQuery := backendUrl.Query()
-	for key, value := range queryParams {
-		backendUrlQuery.Add(key, value)
+	for key, values := range queryParams {
+		for _, value := range values {
+			backendUrlQuery.Add(key, value)
+		}
 	}
 
 	backendUrl.RawQuery = backendUrlQuery.Encode()
 
-	request, err := http.NewRequest(route.Method, backendUrl.String(), body)
+	request, err := http.NewRequestWithContext(requestCtx, route.Method, backendUrl.String(), body)
 	if err != nil {
 		return nil, err
 	}
-	defer request.Body.Close()
 
-	additionalHeaders := make(map[string]string)
-	if backend.PassHeaders || route.PassHeaders {
-		additionalHeaders = requestHeaders
+	request.Header = headers.Clone()
+	request.Trailer = trailer
+	// Without the length every body would be sent chunked, which many backends reject
+	request.ContentLength = contentLength
+
+	permit, err := acquireCircuitPermit(upstream.breaker, upstream.routeBreaker(route))
+	if err != nil {
+		return nil, err
 	}
 
-	headers := b.mergeHeaders(backend.Headers, route.Headers, additionalHeaders)
-	for key, value := range headers {
-		request.Header.Add(key, value)
+	client := http.Client{
+		Transport: upstream.transport,
+	}
+
+	target.acquire()
+	start := time.Now()
+
+	response, err := client.Do(request)
+	if err != nil {
+		target.release()
+
+		if clientCtx.Err() != nil {
+			permit.cancel()
+		} else {
+			permit.record(true, time.Since(start))
+			target.recordTrafficResult(upstream.outlierDetection, true)
+		}
+
+		return nil, err
 	}
 
-	request.Header.Add("X-Api-Gatekeeper-User", userId)
-	request.Header.Add("X-Api-Gatekeeper-Request", userId)
+	failed := response.StatusCode >= http.StatusInternalServerError
+	permit.record(failed, time.Since(start))
+	target.recordTrafficResult(upstream.outlierDetection, failed)
+
+	response.Body = wrapBodyOnClose(response.Body, target.release)
 
-	return client.Do(request)
+	return response, nil
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+func TestDoRequestToBackendRoutePreservesMultiValuedQueryParams(t *testing.T) {
+	var received url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query()
+	}))
+	defer server.Close()
+
+	backend := config.Backend{Host: server.URL}
+	route := config.Route{Method: http.MethodGet, BackendPath: "/items"}
+	queryParams := url.Values{"tag": {"a", "b", "c"}}
+
+	response, err := NewBackend([]config.Backend{backend}, nil, "").DoRequestToBackendRoute(
+		context.Background(), backend, route, model.BackendRequestParams{
+			Body:        http.NoBody,
+			Headers:     http.Header{},
+			QueryParams: queryParams,
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	if got := received["tag"]; !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("expected tag=[a b c], got %v", got)
+	}
+}
+
+func TestDoRequestToBackendRoutePreservesMultiValuedHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	backend := config.Backend{Host: server.URL, PassHeaders: true}
+	route := config.Route{Method: http.MethodGet, BackendPath: "/items"}
+	requestHeaders := http.Header{
+		"Accept":          {"application/json", "text/plain"},
+		"Via":             {"1.1 proxy-a", "1.1 proxy-b"},
+		"X-Forwarded-For": {"203.0.113.9"},
+		"Connection":      {"X-Hop"},
+		"X-Hop":           {"should-not-be-forwarded"},
+	}
+
+	response, err := NewBackend([]config.Backend{backend}, nil, "").DoRequestToBackendRoute(
+		context.Background(), backend, route, model.BackendRequestParams{
+			Body:        http.NoBody,
+			Headers:     requestHeaders,
+			QueryParams: url.Values{},
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	if got := received.Values("Accept"); !slices.Equal(got, []string{"application/json", "text/plain"}) {
+		t.Errorf("expected both Accept values, got %v", got)
+	}
+
+	if got := received.Values("Via"); !slices.Equal(got, []string{"1.1 proxy-a", "1.1 proxy-b"}) {
+		t.Errorf("expected both Via values, got %v", got)
+	}
+
+	if got := received.Get("X-Forwarded-For"); got != "" {
+		t.Errorf("expected client forwarding header to be replaced, got %q", got)
+	}
+
+	if got := received.Get("X-Hop"); got != "" {
+		t.Errorf("expected hop-by-hop header to be removed, got %q", got)
+	}
+}
+
+func TestDoRequestToBackendRouteForwardsContentLength(t *testing.T) {
+	tests := []struct {
+		name                  string
+		clientContentLength   int64
+		retry                 config.Retry
+		wantContentLength     int64
+		wantTransferEncodings []string
+	}{
+		{"known length", 7, config.Retry{}, 7, nil},
+		{"unknown length", -1, config.Retry{}, -1, []string{"chunked"}},
+		{"buffered body", -1, config.Retry{MaxAttempts: 2, BodyBufferBytes: 16}, 7, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var contentLength int64
+			var transferEncodings []string
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentLength = r.ContentLength
+				transferEncodings = r.TransferEncoding
+				received, _ := io.ReadAll(r.Body)
+				body = string(received)
+			}))
+			defer server.Close()
+
+			backend := config.Backend{Host: server.URL}
+			route := config.Route{Method: http.MethodPut, BackendPath: "/items", Retry: test.retry}
+
+			response, err := NewBackend([]config.Backend{backend}, nil, "").DoRequestToBackendRoute(
+				context.Background(), backend, route, model.BackendRequestParams{
+					Body:          io.NopCloser(strings.NewReader("payload")),
+					ContentLength: test.clientContentLength,
+					Headers:       http.Header{},
+					QueryParams:   url.Values{},
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			response.Body.Close()
+
+			if contentLength != test.wantContentLength {
+				t.Errorf("expected content length %d, got %d", test.wantContentLength, contentLength)
+			}
+
+			if !slices.Equal(transferEncodings, test.wantTransferEncodings) {
+				t.Errorf("expected transfer encodings %v, got %v", test.wantTransferEncodings, transferEncodings)
+			}
+
+			if body != "payload" {
+				t.Errorf("expected the whole body, got %q", body)
+			}
+		})
+	}
+}
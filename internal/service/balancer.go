@@ -0,0 +1,165 @@
+package service
+
+import (
+	"hash/crc32"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+const consistentHashVirtualNodes = 100
+
+type balancer struct {
+	strategy config.LoadBalancingStrategy
+	targets  []*upstreamTarget
+
+	roundRobinCounter atomic.Uint64
+
+	weightedMu             sync.Mutex
+	weightedCurrentWeights []int
+
+	hashRing      []uint32
+	hashRingNodes map[uint32]*upstreamTarget
+}
+
+func newBalancer(cfg config.LoadBalancing, targets []*upstreamTarget) *balancer {
+	b := &balancer{
+		strategy:               cfg.Strategy,
+		targets:                targets,
+		weightedCurrentWeights: make([]int, len(targets)),
+	}
+
+	if b.strategy == config.LoadBalancingConsistentHash {
+		b.buildHashRing()
+	}
+
+	return b
+}
+
+func (b *balancer) buildHashRing() {
+	b.hashRingNodes = make(map[uint32]*upstreamTarget)
+
+	for _, target := range b.targets {
+		for i := range consistentHashVirtualNodes * target.weight {
+			hash := crc32.ChecksumIEEE([]byte(target.host + "#" + strconv.Itoa(i)))
+			if _, exists := b.hashRingNodes[hash]; exists {
+				continue
+			}
+
+			b.hashRingNodes[hash] = target
+			b.hashRing = append(b.hashRing, hash)
+		}
+	}
+
+	slices.Sort(b.hashRing)
+}
+
+// pick Returns the next available target following the balancer strategy, the key is only
+// used by the consistent hash strategy. Returns nil if there is no available target
+func (b *balancer) pick(key string) *upstreamTarget {
+	available := make([]*upstreamTarget, 0, len(b.targets))
+	for _, target := range b.targets {
+		if target.isAvailable() {
+			available = append(available, target)
+		}
+	}
+
+	if len(available) == 0 {
+		return nil
+	}
+
+	switch b.strategy {
+	case config.LoadBalancingWeighted:
+		return b.pickWeighted(available)
+	case config.LoadBalancingLeastConnections:
+		return b.pickLeastConnections(available)
+	case config.LoadBalancingRandomTwoChoices:
+		return b.pickRandomTwoChoices(available)
+	case config.LoadBalancingConsistentHash:
+		if key != "" {
+			return b.pickConsistentHash(key)
+		}
+	}
+
+	return b.pickRoundRobin(available)
+}
+
+func (b *balancer) pickRoundRobin(available []*upstreamTarget) *upstreamTarget {
+	n := b.roundRobinCounter.Add(1) - 1
+
+	return available[n%uint64(len(available))]
+}
+
+// pickWeighted Uses the smooth weighted round-robin algorithm, distributing the requests
+// proportionally to the targets weights without sending bursts to the heavier targets
+func (b *balancer) pickWeighted(available []*upstreamTarget) *upstreamTarget {
+	b.weightedMu.Lock()
+	defer b.weightedMu.Unlock()
+
+	totalWeight := 0
+	bestIndex := -1
+	for i, target := range b.targets {
+		if !slices.Contains(available, target) {
+			continue
+		}
+
+		b.weightedCurrentWeights[i] += target.weight
+		totalWeight += target.weight
+
+		if bestIndex == -1 || b.weightedCurrentWeights[i] > b.weightedCurrentWeights[bestIndex] {
+			bestIndex = i
+		}
+	}
+
+	b.weightedCurrentWeights[bestIndex] -= totalWeight
+
+	return b.targets[bestIndex]
+}
+
+func (b *balancer) pickLeastConnections(available []*upstreamTarget) *upstreamTarget {
+	best := available[0]
+	for _, target := range available[1:] {
+		if target.activeConnections.Load() < best.activeConnections.Load() {
+			best = target
+		}
+	}
+
+	return best
+}
+
+func (b *balancer) pickRandomTwoChoices(available []*upstreamTarget) *upstreamTarget {
+	if len(available) == 1 {
+		return available[0]
+	}
+
+	firstIndex := rand.IntN(len(available))
+	secondIndex := (firstIndex + 1 + rand.IntN(len(available)-1)) % len(available)
+
+	first, second := available[firstIndex], available[secondIndex]
+
+	if second.activeConnections.Load() < first.activeConnections.Load() {
+		return second
+	}
+
+	return first
+}
+
+// pickConsistentHash Walks the hash ring clockwise from the key hash until an available target
+// is found, so the same key is always sent to the same target while it stays available
+func (b *balancer) pickConsistentHash(key string) *upstreamTarget {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start, _ := slices.BinarySearch(b.hashRing, hash)
+
+	for i := range b.hashRing {
+		target := b.hashRingNodes[b.hashRing[(start+i)%len(b.hashRing)]]
+		if target.isAvailable() {
+			return target
+		}
+	}
+
+	return nil
+}
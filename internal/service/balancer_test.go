@@ -0,0 +1,191 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+func newTestTargets(weights ...int) []*upstreamTarget {
+	targets := make([]*upstreamTarget, len(weights))
+	for i, weight := range weights {
+		targets[i] = &upstreamTarget{host: "http://target-" + strconv.Itoa(i), weight: weight}
+	}
+
+	return targets
+}
+
+func pickHosts(b *balancer, key string, n int) []string {
+	hosts := make([]string, n)
+	for i := range hosts {
+		hosts[i] = b.pick(key).host
+	}
+
+	return hosts
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	targets := newTestTargets(1, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingRoundRobin}, targets)
+
+	got := pickHosts(b, "", 6)
+	for i, host := range got {
+		if want := targets[i%3].host; host != want {
+			t.Fatalf("expected pick %d to be %s, got %v", i, want, got)
+		}
+	}
+
+	targets[1].unhealthy.Store(true)
+	for _, host := range pickHosts(b, "", 10) {
+		if host == targets[1].host {
+			t.Fatalf("expected the unhealthy target to be skipped")
+		}
+	}
+}
+
+func TestBalancerSmoothWeightedRoundRobin(t *testing.T) {
+	targets := newTestTargets(5, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingWeighted}, targets)
+
+	// The smooth weighted round-robin interleaves the lighter targets instead of sending every
+	// request of the heavier target in a burst
+	heavy, first, second := targets[0].host, targets[1].host, targets[2].host
+	want := []string{heavy, heavy, first, heavy, second, heavy, heavy}
+
+	got := pickHosts(b, "", len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected sequence %v, got %v", want, got)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, host := range pickHosts(b, "", 70) {
+		counts[host]++
+	}
+
+	if counts[heavy] != 50 || counts[first] != 10 || counts[second] != 10 {
+		t.Errorf("expected a 50/10/10 distribution, got %v", counts)
+	}
+}
+
+func TestBalancerWeightedSkipsUnavailableTargets(t *testing.T) {
+	targets := newTestTargets(5, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingWeighted}, targets)
+	targets[0].unhealthy.Store(true)
+
+	counts := map[string]int{}
+	for _, host := range pickHosts(b, "", 10) {
+		counts[host]++
+	}
+
+	if counts[targets[0].host] != 0 || counts[targets[1].host] != 5 || counts[targets[2].host] != 5 {
+		t.Errorf("expected the remaining targets to split the requests, got %v", counts)
+	}
+}
+
+func TestBalancerLeastConnections(t *testing.T) {
+	targets := newTestTargets(1, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingLeastConnections}, targets)
+
+	targets[0].activeConnections.Store(3)
+	targets[1].activeConnections.Store(1)
+	targets[2].activeConnections.Store(2)
+
+	if got := b.pick(""); got != targets[1] {
+		t.Errorf("expected %s, got %s", targets[1].host, got.host)
+	}
+}
+
+func TestBalancerRandomTwoChoices(t *testing.T) {
+	targets := newTestTargets(1, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingRandomTwoChoices}, targets)
+
+	targets[0].activeConnections.Store(10)
+	targets[1].activeConnections.Store(1)
+	targets[2].activeConnections.Store(2)
+
+	// Both choices are always distinct, so the busiest target can never win a comparison
+	counts := map[string]int{}
+	for _, host := range pickHosts(b, "", 200) {
+		counts[host]++
+	}
+
+	if counts[targets[0].host] != 0 {
+		t.Errorf("expected the busiest target to never be picked, got %v", counts)
+	}
+
+	if counts[targets[1].host] <= counts[targets[2].host] {
+		t.Errorf("expected the least busy target to be picked the most, got %v", counts)
+	}
+
+	single := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingRandomTwoChoices}, targets[:1])
+	if got := single.pick(""); got != targets[0] {
+		t.Errorf("expected the only target to be picked, got %v", got)
+	}
+}
+
+func TestBalancerConsistentHash(t *testing.T) {
+	targets := newTestTargets(1, 1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingConsistentHash}, targets)
+
+	if len(b.hashRing) != 3*consistentHashVirtualNodes {
+		t.Fatalf("expected %d ring nodes, got %d", 3*consistentHashVirtualNodes, len(b.hashRing))
+	}
+
+	keys := make([]string, 100)
+	assigned := make(map[string]*upstreamTarget, len(keys))
+	for i := range keys {
+		keys[i] = "user-" + strconv.Itoa(i)
+		assigned[keys[i]] = b.pick(keys[i])
+
+		if again := b.pick(keys[i]); again != assigned[keys[i]] {
+			t.Fatalf("expected key %s to stick to %s, got %s", keys[i], assigned[keys[i]].host, again.host)
+		}
+	}
+
+	// Only the keys of an unavailable target move, every other key keeps its target
+	targets[0].unhealthy.Store(true)
+	for _, key := range keys {
+		got := b.pick(key)
+		if got == targets[0] {
+			t.Fatalf("expected key %s to leave the unhealthy target", key)
+		}
+
+		if assigned[key] != targets[0] && got != assigned[key] {
+			t.Errorf("expected key %s to stay on %s, got %s", key, assigned[key].host, got.host)
+		}
+	}
+
+	targets[0].unhealthy.Store(false)
+	for _, key := range keys {
+		if got := b.pick(key); got != assigned[key] {
+			t.Errorf("expected key %s to return to %s once it recovers, got %s", key, assigned[key].host, got.host)
+		}
+	}
+}
+
+func TestBalancerConsistentHashWithoutKeyFallsBackToRoundRobin(t *testing.T) {
+	targets := newTestTargets(1, 1)
+	b := newBalancer(config.LoadBalancing{Strategy: config.LoadBalancingConsistentHash}, targets)
+
+	got := pickHosts(b, "", 4)
+	if got[0] == got[1] || got[0] != got[2] || got[1] != got[3] {
+		t.Errorf("expected the targets to alternate, got %v", got)
+	}
+}
+
+func TestBalancerWithoutAvailableTargets(t *testing.T) {
+	for _, strategy := range config.ValidLoadBalancingStrategies {
+		targets := newTestTargets(1, 1)
+		b := newBalancer(config.LoadBalancing{Strategy: strategy}, targets)
+		for _, target := range targets {
+			target.unhealthy.Store(true)
+		}
+
+		if got := b.pick("key"); got != nil {
+			t.Errorf("expected no target for strategy %s, got %s", strategy, got.host)
+		}
+	}
+}
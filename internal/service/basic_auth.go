@@ -1,8 +1,7 @@
 package service
 
 import (
-	"fmt"
-	"slices"
+	"errors"
 
 	"github.com/gustapinto/api-gatekeeper/internal/model"
 	httputil "github.com/gustapinto/api-gatekeeper/pkg/http_util"
@@ -31,6 +30,10 @@ func (s *BasicAuth) AuthenticateToken(token string) (model.User, error) {
 
 	user, err := s.userRepository.GetByLogin(login)
 	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			compareDummyPassword(password)
+		}
+
 		return model.User{}, err
 	}
 
@@ -42,11 +45,5 @@ func (s *BasicAuth) AuthenticateToken(token string) (model.User, error) {
 }
 
 func (s *BasicAuth) Authorize(user model.User, requiredScopes []string) error {
-	for _, requiredScope := range requiredScopes {
-		if !slices.Contains(user.Scopes, requiredScope) {
-			return fmt.Errorf("missing %s scope", requiredScope)
-		}
-	}
-
-	return nil
+	return authorizeScopes(user, requiredScopes)
 }
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitOpenError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrCircuitOpen.Error(), e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+type circuitBreaker struct {
+	cfg config.CircuitBreaker
+	now func() time.Time
+
+	mu         sync.Mutex
+	state      circuitState
+	generation uint64
+	openedAt   time.Time
+
+	windowStart         time.Time
+	requests            int
+	failures            int
+	slowCalls           int
+	consecutiveFailures int
+
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+}
+
+// newCircuitBreaker Returns nil if the circuit breaker is disabled, a nil breaker allows every request
+func newCircuitBreaker(cfg config.CircuitBreaker) *circuitBreaker {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	return &circuitBreaker{
+		cfg:         cfg,
+		now:         time.Now,
+		windowStart: time.Now(),
+	}
+}
+
+// allow Reports if a request can be done, returning the breaker generation that must be passed
+// to record once the request finishes
+func (c *circuitBreaker) allow() (uint64, error) {
+	if c == nil {
+		return 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expireOpenState(now)
+
+	if c.state == circuitOpen {
+		return 0, &CircuitOpenError{RetryAfter: c.openedAt.Add(c.cfg.OpenDuration()).Sub(now)}
+	}
+
+	if c.state == circuitHalfOpen {
+		if c.halfOpenInFlight+c.halfOpenSuccesses >= c.cfg.HalfOpenProbes {
+			return 0, &CircuitOpenError{RetryAfter: time.Second}
+		}
+
+		c.halfOpenInFlight++
+	}
+
+	return c.generation, nil
+}
+
+// record Registers a request result, results from previous generations are ignored as they
+// were started before the last state transition
+func (c *circuitBreaker) record(generation uint64, failed bool, latency time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+
+	if c.state == circuitHalfOpen {
+		c.halfOpenInFlight--
+
+		if failed {
+			c.transition(circuitOpen, now)
+			return
+		}
+
+		c.halfOpenSuccesses++
+		if c.halfOpenSuccesses >= c.cfg.HalfOpenProbes {
+			c.transition(circuitClosed, now)
+		}
+
+		return
+	}
+
+	if now.Sub(c.windowStart) > c.cfg.Window() {
+		c.resetWindow(now)
+	}
+
+	c.requests++
+
+	if failed {
+		c.failures++
+		c.consecutiveFailures++
+	} else {
+		c.consecutiveFailures = 0
+	}
+
+	if c.cfg.SlowCallDurationMilliseconds > 0 && latency >= c.cfg.SlowCallDuration() {
+		c.slowCalls++
+	}
+
+	if c.shouldTrip() {
+		c.transition(circuitOpen, now)
+	}
+}
+
+// cancel Releases a permit without recording a result, used when the request was aborted by the client
+func (c *circuitBreaker) cancel(generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation == c.generation && c.state == circuitHalfOpen {
+		c.halfOpenInFlight--
+	}
+}
+
+func (c *circuitBreaker) shouldTrip() bool {
+	if c.cfg.ConsecutiveFailures > 0 && c.consecutiveFailures >= c.cfg.ConsecutiveFailures {
+		return true
+	}
+
+	if c.requests < c.cfg.MinimumRequests {
+		return false
+	}
+
+	if c.cfg.FailureRate > 0 && float64(c.failures)/float64(c.requests) >= c.cfg.FailureRate {
+		return true
+	}
+
+	if c.cfg.SlowCallRate > 0 && c.cfg.SlowCallDurationMilliseconds > 0 &&
+		float64(c.slowCalls)/float64(c.requests) >= c.cfg.SlowCallRate {
+		return true
+	}
+
+	return false
+}
+
+func (c *circuitBreaker) transition(state circuitState, now time.Time) {
+	c.state = state
+	c.generation++
+	c.halfOpenInFlight = 0
+	c.halfOpenSuccesses = 0
+	c.consecutiveFailures = 0
+	c.resetWindow(now)
+
+	if state == circuitOpen {
+		c.openedAt = now
+	}
+}
+
+// expireOpenState Moves an open breaker to half-open once its open period is over
+func (c *circuitBreaker) expireOpenState(now time.Time) {
+	if c.state == circuitOpen && !now.Before(c.openedAt.Add(c.cfg.OpenDuration())) {
+		c.transition(circuitHalfOpen, now)
+	}
+}
+
+func (c *circuitBreaker) resetWindow(now time.Time) {
+	c.windowStart = now
+	c.requests = 0
+	c.failures = 0
+	c.slowCalls = 0
+}
+
+func (c *circuitBreaker) currentState() circuitState {
+	if c == nil {
+		return circuitClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expireOpenState(c.now())
+
+	return c.state
+}
+
+// circuitPermit Holds the permits of the backend and route circuit breakers for a single request
+type circuitPermit struct {
+	backendBreaker    *circuitBreaker
+	backendGeneration uint64
+	routeBreaker      *circuitBreaker
+	routeGeneration   uint64
+}
+
+func acquireCircuitPermit(backendBreaker, routeBreaker *circuitBreaker) (*circuitPermit, error) {
+	backendGeneration, err := backendBreaker.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	routeGeneration, err := routeBreaker.allow()
+	if err != nil {
+		backendBreaker.cancel(backendGeneration)
+		return nil, err
+	}
+
+	return &circuitPermit{
+		backendBreaker:    backendBreaker,
+		backendGeneration: backendGeneration,
+		routeBreaker:      routeBreaker,
+		routeGeneration:   routeGeneration,
+	}, nil
+}
+
+func (p *circuitPermit) record(failed bool, latency time.Duration) {
+	p.backendBreaker.record(p.backendGeneration, failed, latency)
+	p.routeBreaker.record(p.routeGeneration, failed, latency)
+}
+
+func (p *circuitPermit) cancel() {
+	p.backendBreaker.cancel(p.backendGeneration)
+	p.routeBreaker.cancel(p.routeGeneration)
+}
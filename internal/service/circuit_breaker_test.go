@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestCircuitBreaker(t *testing.T, cfg config.CircuitBreaker) (*circuitBreaker, *fakeClock) {
+	t.Helper()
+
+	cfg.Normalize()
+	breaker := newCircuitBreaker(cfg)
+	if breaker == nil {
+		t.Fatalf("expected the circuit breaker to be enabled")
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	breaker.now = clock.Now
+	breaker.windowStart = clock.now
+
+	return breaker, clock
+}
+
+func recordResults(t *testing.T, breaker *circuitBreaker, failed bool, latency time.Duration, n int) {
+	t.Helper()
+
+	for range n {
+		generation, err := breaker.allow()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		breaker.record(generation, failed, latency)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker(config.CircuitBreaker{})
+	if breaker != nil {
+		t.Fatalf("expected a nil circuit breaker")
+	}
+
+	if _, err := breaker.allow(); err != nil {
+		t.Errorf("expected a nil breaker to allow every request, got %v", err)
+	}
+
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Errorf("expected a nil breaker to be closed, got %s", state)
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(t, config.CircuitBreaker{
+		ConsecutiveFailures: 3,
+		OpenSeconds:         10,
+		HalfOpenProbes:      2,
+	})
+
+	recordResults(t, breaker, true, 0, 2)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Fatalf("expected closed before the threshold, got %s", state)
+	}
+
+	recordResults(t, breaker, true, 0, 1)
+	if state := breaker.currentState(); state != circuitOpen {
+		t.Fatalf("expected open after the threshold, got %s", state)
+	}
+
+	clock.Advance(4 * time.Second)
+	_, err := breaker.allow()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a circuit open error, got %v", err)
+	}
+	if openErr.RetryAfter != 6*time.Second {
+		t.Errorf("expected a retry after of 6s, got %s", openErr.RetryAfter)
+	}
+
+	// The reported state must not stay open after the open period, even without new requests
+	clock.Advance(6 * time.Second)
+	if state := breaker.currentState(); state != circuitHalfOpen {
+		t.Fatalf("expected half-open after the open period, got %s", state)
+	}
+
+	first, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error on the first probe: %v", err)
+	}
+	second, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error on the second probe: %v", err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the probes to be limited, got %v", err)
+	}
+
+	breaker.record(first, false, 0)
+	if state := breaker.currentState(); state != circuitHalfOpen {
+		t.Fatalf("expected half-open until every probe succeeds, got %s", state)
+	}
+
+	breaker.record(second, false, 0)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Fatalf("expected closed after the probes succeed, got %s", state)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(t, config.CircuitBreaker{ConsecutiveFailures: 1, OpenSeconds: 10})
+
+	recordResults(t, breaker, true, 0, 1)
+	clock.Advance(10 * time.Second)
+
+	generation, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	breaker.record(generation, true, 0)
+	if state := breaker.currentState(); state != circuitOpen {
+		t.Fatalf("expected a failed probe to reopen the breaker, got %s", state)
+	}
+
+	clock.Advance(9 * time.Second)
+	if state := breaker.currentState(); state != circuitOpen {
+		t.Errorf("expected the open period to restart, got %s", state)
+	}
+}
+
+func TestCircuitBreakerCanceledProbeReleasesPermit(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(t, config.CircuitBreaker{ConsecutiveFailures: 1, OpenSeconds: 1, HalfOpenProbes: 1})
+
+	recordResults(t, breaker, true, 0, 1)
+	clock.Advance(time.Second)
+
+	generation, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	breaker.cancel(generation)
+
+	if _, err := breaker.allow(); err != nil {
+		t.Errorf("expected the canceled probe permit to be released, got %v", err)
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(t, config.CircuitBreaker{FailureRate: 0.5, MinimumRequests: 4, WindowSeconds: 60})
+
+	recordResults(t, breaker, true, 0, 3)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Fatalf("expected closed below the minimum requests, got %s", state)
+	}
+
+	// A new window discards the previous failures
+	clock.Advance(61 * time.Second)
+	recordResults(t, breaker, false, 0, 2)
+	recordResults(t, breaker, true, 0, 1)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Fatalf("expected closed below the minimum requests of the new window, got %s", state)
+	}
+
+	recordResults(t, breaker, true, 0, 1)
+	if state := breaker.currentState(); state != circuitOpen {
+		t.Errorf("expected open at the failure rate, got %s", state)
+	}
+}
+
+func TestCircuitBreakerSlowCallRate(t *testing.T) {
+	breaker, _ := newTestCircuitBreaker(t, config.CircuitBreaker{
+		SlowCallDurationMilliseconds: 100,
+		SlowCallRate:                 0.5,
+		MinimumRequests:              4,
+	})
+
+	recordResults(t, breaker, false, 10*time.Millisecond, 2)
+	recordResults(t, breaker, false, 100*time.Millisecond, 1)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Fatalf("expected closed below the minimum requests, got %s", state)
+	}
+
+	recordResults(t, breaker, false, time.Second, 1)
+	if state := breaker.currentState(); state != circuitOpen {
+		t.Errorf("expected open at the slow call rate, got %s", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(t, config.CircuitBreaker{ConsecutiveFailures: 1, OpenSeconds: 1})
+
+	stale, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recordResults(t, breaker, true, 0, 1)
+	clock.Advance(time.Second)
+
+	probe, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The request started before the breaker opened must not affect the probe
+	breaker.record(stale, true, 0)
+	if state := breaker.currentState(); state != circuitHalfOpen {
+		t.Fatalf("expected the stale result to be ignored, got %s", state)
+	}
+
+	breaker.record(probe, false, 0)
+	if state := breaker.currentState(); state != circuitClosed {
+		t.Errorf("expected closed after the probe succeeds, got %s", state)
+	}
+}
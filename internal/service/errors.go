@@ -0,0 +1,28 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("already exists")
+	ErrUnauthenticated = errors.New("invalid credentials")
+	ErrUnprocessable   = errors.New("unprocessable")
+)
+
+// ValidationError Is an invalid input, like a missing or blank required parameter
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func newValidationError(format string, args ...any) error {
+	return &ValidationError{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
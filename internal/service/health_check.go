@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+type healthChecker struct {
+	cfg     config.HealthCheck
+	client  *http.Client
+	targets []*upstreamTarget
+	logger  *slog.Logger
+}
+
+func newHealthChecker(upstream *backendUpstream, logger *slog.Logger) *healthChecker {
+	return &healthChecker{
+		cfg: upstream.healthCheck,
+		client: &http.Client{
+			Transport: upstream.transport,
+			Timeout:   upstream.healthCheck.Timeout(),
+		},
+		targets: upstream.targets,
+		logger:  logger,
+	}
+}
+
+func (c *healthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval())
+	defer ticker.Stop()
+
+	c.checkAll(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkAll(ctx)
+		}
+	}
+}
+
+func (c *healthChecker) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, target := range c.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.check(ctx, target)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (c *healthChecker) check(ctx context.Context, target *upstreamTarget) {
+	err := c.probe(ctx, target)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if !target.recordHealthCheck(c.cfg, err) {
+		return
+	}
+
+	targetLogger := c.logger.With("target", target.host)
+	if target.isAvailable() {
+		targetLogger.Info("Backend target is healthy, adding it back to rotation")
+	} else {
+		targetLogger.Warn("Backend target is unhealthy, removing it from rotation", "error", err)
+	}
+}
+
+func (c *healthChecker) probe(ctx context.Context, target *upstreamTarget) error {
+	healthCheckUrl, err := url.JoinPath(target.host, c.cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, c.cfg.Method, healthCheckUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, response.Body)
+
+	if !c.cfg.IsExpectedStatus(response.StatusCode) {
+		return fmt.Errorf("unexpected health check status %d", response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type identityAssertionClaims struct {
+	Login      string            `json:"login,omitempty"`
+	Scopes     []string          `json:"scopes,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Route      string            `json:"route,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IdentityAssertion Mints the short-lived JWTs that assert the identity of the user to the
+// backends, they are signed with a key dedicated to it and verifiable through the JWKS
+type IdentityAssertion struct {
+	cfg           config.IdentityAssertion
+	key           crypto.Signer
+	signingMethod jwt.SigningMethod
+	ephemeral     bool
+}
+
+// NewIdentityAssertion Loads the signing key from the configured PEM file (RSA or ECDSA), if no
+// file is configured an ephemeral ECDSA key is generated, it will change on every restart
+func NewIdentityAssertion(cfg config.IdentityAssertion) (*IdentityAssertion, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	key, ephemeral, err := loadIdentityAssertionKey(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	signingMethod, err := identityAssertionSigningMethod(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityAssertion{
+		cfg:           cfg,
+		key:           key,
+		signingMethod: signingMethod,
+		ephemeral:     ephemeral,
+	}, nil
+}
+
+func loadIdentityAssertionKey(path string) (crypto.Signer, bool, error) {
+	if path == "" {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return key, true, err
+	}
+
+	keyPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyPEM); err == nil {
+		return rsaKey, false, nil
+	}
+
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, false, errors.New("identity assertion private key must be a PEM encoded RSA or ECDSA key")
+	}
+
+	return ecKey, false, nil
+}
+
+func identityAssertionSigningMethod(key crypto.Signer) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	}
+
+	return nil, errors.New("unsupported identity assertion key type")
+}
+
+// IsEphemeral Reports if the signing key was generated on startup
+func (i *IdentityAssertion) IsEphemeral() bool {
+	return i != nil && i.ephemeral
+}
+
+// apply Replaces any assertion sent by the client with a new one for the user, public routes
+// requests have no user, so they never carry an assertion
+func (i *IdentityAssertion) apply(
+	headers http.Header,
+	backend config.Backend,
+	route config.Route,
+	params model.BackendRequestParams,
+) error {
+	if i == nil {
+		return nil
+	}
+
+	headers.Del(i.cfg.Header)
+
+	if params.User.ID == "" {
+		return nil
+	}
+
+	token, err := i.sign(backend, route, params)
+	if err != nil {
+		return fmt.Errorf("failed to sign identity assertion: %w", err)
+	}
+
+	headers.Set(i.cfg.Header, token)
+
+	return nil
+}
+
+func (i *IdentityAssertion) sign(backend config.Backend, route config.Route, params model.BackendRequestParams) (string, error) {
+	properties := make(map[string]string)
+	for _, property := range i.cfg.Properties {
+		if value, exists := params.User.Properties[property]; exists {
+			properties[property] = value
+		}
+	}
+
+	now := time.Now()
+	claims := identityAssertionClaims{
+		Login:      params.User.Login,
+		Scopes:     params.User.Scopes,
+		Properties: properties,
+		Route:      route.Name(),
+		RequestID:  params.RequestID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    i.cfg.Issuer,
+			Subject:   params.User.ID,
+			Audience:  jwt.ClaimStrings{backend.Name},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.cfg.Expiration())),
+		},
+	}
+
+	token := jwt.NewWithClaims(i.signingMethod, claims)
+	token.Header["kid"] = i.cfg.KeyID
+
+	return token.SignedString(i.key)
+}
+
+// JWKS Returns the public key set that verifies the assertions, it is empty when they are disabled
+func (i *IdentityAssertion) JWKS() model.JSONWebKeySet {
+	jwks := model.JSONWebKeySet{
+		Keys: make([]model.JSONWebKey, 0),
+	}
+
+	if i == nil {
+		return jwks
+	}
+
+	jwk := model.JSONWebKey{
+		Use:       "sig",
+		Algorithm: i.signingMethod.Alg(),
+		KeyID:     i.cfg.KeyID,
+	}
+
+	switch publicKey := i.key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeJWKInt(publicKey.N, 0)
+		jwk.E = encodeJWKInt(big.NewInt(int64(publicKey.E)), 0)
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+
+		jwk.KeyType = "EC"
+		jwk.Curve = publicKey.Curve.Params().Name
+		jwk.X = encodeJWKInt(publicKey.X, size)
+		jwk.Y = encodeJWKInt(publicKey.Y, size)
+	}
+
+	jwks.Keys = append(jwks.Keys, jwk)
+
+	return jwks
+}
+
+// encodeJWKInt Encodes the integer as unpadded base64url, left padded with zeros to the size
+func encodeJWKInt(n *big.Int, size int) string {
+	bytes := n.Bytes()
+	if len(bytes) < size {
+		bytes = append(make([]byte, size-len(bytes)), bytes...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+func writeTestKeyPEM(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	}
+
+	path := filepath.Join(t.TempDir(), "assertion.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return path
+}
+
+// publicKeyFromJWK Rebuilds the public key like a backend would, from the published JWK only
+func publicKeyFromJWK(t *testing.T, jwk model.JSONWebKey) crypto.PublicKey {
+	t.Helper()
+
+	decode := func(value string) []byte {
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			t.Fatalf("expected an unpadded base64url value, got %q", value)
+		}
+
+		return decoded
+	}
+
+	switch jwk.KeyType {
+	case "RSA":
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(decode(jwk.N)),
+			E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64()),
+		}
+	case "EC":
+		if jwk.Curve != "P-256" {
+			t.Fatalf("expected the P-256 curve, got %q", jwk.Curve)
+		}
+
+		x, y := decode(jwk.X), decode(jwk.Y)
+		if len(x) != 32 || len(y) != 32 {
+			t.Fatalf("expected 32 byte coordinates, got %d and %d", len(x), len(y))
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	}
+
+	t.Fatalf("unexpected key type %q", jwk.KeyType)
+	return nil
+}
+
+func TestIdentityAssertionVerifiesAgainstTheJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		wantAlg string
+	}{
+		{"rsa", rsaKey, "RS256"},
+		{"p-256", ecKey, "ES256"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.IdentityAssertion{
+				Enabled:        true,
+				KeyID:          "key-1",
+				PrivateKeyPath: writeTestKeyPEM(t, test.key),
+				Properties:     []string{"tenant"},
+			}
+			cfg.Normalize()
+
+			assertion, err := NewIdentityAssertion(cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if assertion.IsEphemeral() {
+				t.Errorf("expected the configured key to not be ephemeral")
+			}
+
+			var received http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+			}))
+			defer server.Close()
+
+			backend := config.Backend{Name: "orders", Host: server.URL, PassHeaders: true}
+			route := config.Route{Method: http.MethodGet, GatekeeperPath: "/orders", BackendPath: "/orders"}
+			user := model.User{
+				ID:         "user-id",
+				Login:      "alice",
+				Scopes:     []string{"orders.read"},
+				Properties: map[string]string{"tenant": "acme", "secret": "hidden"},
+			}
+
+			b := NewBackend([]config.Backend{backend}, assertion, "")
+			response, err := b.DoRequestToBackendRoute(context.Background(), backend, route, model.BackendRequestParams{
+				Body:        http.NoBody,
+				Headers:     http.Header{cfg.Header: {"spoofed"}},
+				QueryParams: url.Values{},
+				User:        user,
+				RequestID:   "request-id",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+
+			if values := received.Values(cfg.Header); len(values) != 1 || values[0] == "spoofed" {
+				t.Fatalf("expected the client assertion to be replaced, got %v", values)
+			}
+
+			// The backend only knows the published JWKS
+			jwksJSON, err := json.Marshal(b.JWKS())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var jwks model.JSONWebKeySet
+			if err := json.Unmarshal(jwksJSON, &jwks); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Algorithm != test.wantAlg || jwks.Keys[0].Use != "sig" {
+				t.Fatalf("expected a single %s signing key, got %+v", test.wantAlg, jwks.Keys)
+			}
+
+			var claims identityAssertionClaims
+			token, err := jwt.ParseWithClaims(received.Get(cfg.Header), &claims, func(token *jwt.Token) (any, error) {
+				for _, jwk := range jwks.Keys {
+					if jwk.KeyID == token.Header["kid"] && jwk.Algorithm == token.Method.Alg() {
+						return publicKeyFromJWK(t, jwk), nil
+					}
+				}
+
+				return nil, fmt.Errorf("unknown key %v", token.Header["kid"])
+			})
+			if err != nil || !token.Valid {
+				t.Fatalf("expected the assertion to be verified by the JWKS, got %v", err)
+			}
+
+			if token.Method.Alg() != test.wantAlg || token.Header["kid"] != "key-1" {
+				t.Errorf("expected a %s token with the key-1 kid, got %s and %v", test.wantAlg, token.Method.Alg(), token.Header["kid"])
+			}
+
+			if claims.Subject != "user-id" || claims.Login != "alice" || claims.Issuer != "api-gatekeeper" {
+				t.Errorf("unexpected identity claims %+v", claims)
+			}
+
+			if !claims.VerifyAudience("orders", true) {
+				t.Errorf("expected the backend audience, got %v", claims.Audience)
+			}
+
+			if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != time.Minute {
+				t.Errorf("expected a 60s assertion, got %s", lifetime)
+			}
+
+			if len(claims.Properties) != 1 || claims.Properties["tenant"] != "acme" {
+				t.Errorf("expected only the configured properties, got %v", claims.Properties)
+			}
+
+			if claims.RequestID != "request-id" || claims.Route != route.Name() {
+				t.Errorf("expected the request ID and route claims, got %q and %q", claims.RequestID, claims.Route)
+			}
+		})
+	}
+}
+
+func TestIdentityAssertionDropsSpoofedHeaderOfAnonymousRequests(t *testing.T) {
+	cfg := config.IdentityAssertion{Enabled: true}
+	cfg.Normalize()
+
+	assertion, err := NewIdentityAssertion(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headers := http.Header{cfg.Header: {"spoofed"}}
+	if err := assertion.apply(headers, config.Backend{Name: "orders"}, config.Route{}, model.BackendRequestParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if values := headers.Values(cfg.Header); len(values) != 0 {
+		t.Errorf("expected the spoofed assertion to be dropped, got %v", values)
+	}
+}
+
+func TestNewIdentityAssertion(t *testing.T) {
+	disabled, err := NewIdentityAssertion(config.IdentityAssertion{})
+	if err != nil || disabled != nil {
+		t.Fatalf("expected no assertion when disabled, got %v, %v", disabled, err)
+	}
+
+	if keys := disabled.JWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Errorf("expected an empty JWKS when disabled, got %v", keys)
+	}
+
+	ephemeral, err := NewIdentityAssertion(config.IdentityAssertion{Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !ephemeral.IsEphemeral() || ephemeral.signingMethod.Alg() != "ES256" {
+		t.Errorf("expected an ephemeral ES256 key, got %s", ephemeral.signingMethod.Alg())
+	}
+
+	invalidPath := filepath.Join(t.TempDir(), "invalid.pem")
+	os.WriteFile(invalidPath, []byte("not a key"), 0o600)
+	if _, err := NewIdentityAssertion(config.IdentityAssertion{Enabled: true, PrivateKeyPath: invalidPath}); err == nil {
+		t.Errorf("expected an invalid key file to fail")
+	}
+
+	if _, err := NewIdentityAssertion(config.IdentityAssertion{Enabled: true, PrivateKeyPath: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Errorf("expected a missing key file to fail")
+	}
+
+	p224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := identityAssertionSigningMethod(p224Key); err == nil {
+		t.Errorf("expected the P-224 curve to be unsupported")
+	}
+}
+
+func TestEncodeJWKIntPadsToTheSize(t *testing.T) {
+	tests := []struct {
+		n    *big.Int
+		size int
+		want int
+	}{
+		{big.NewInt(1), 32, 32},
+		{new(big.Int).Lsh(big.NewInt(1), 255), 32, 32},
+		{big.NewInt(65537), 0, 3},
+	}
+
+	for _, test := range tests {
+		decoded, err := base64.RawURLEncoding.DecodeString(encodeJWKInt(test.n, test.size))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(decoded) != test.want || new(big.Int).SetBytes(decoded).Cmp(test.n) != 0 {
+			t.Errorf("expected %s encoded in %d bytes, got %x", test.n, test.want, decoded)
+		}
+	}
+}
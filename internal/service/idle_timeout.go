@@ -0,0 +1,44 @@
+package service
+
+import (
+	"io"
+	"time"
+)
+
+// idleTimeout Calls onTimeout if no activity is registered for the timeout duration, a nil
+// idleTimeout never times out
+type idleTimeout struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newIdleTimeout(timeout time.Duration, onTimeout func()) *idleTimeout {
+	if timeout <= 0 {
+		return nil
+	}
+
+	return &idleTimeout{
+		timeout: timeout,
+		timer:   time.AfterFunc(timeout, onTimeout),
+	}
+}
+
+func (t *idleTimeout) touch() {
+	if t != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+func (t *idleTimeout) stop() {
+	if t != nil {
+		t.timer.Stop()
+	}
+}
+
+// wrap Returns a body that registers activity on every successful read
+func (t *idleTimeout) wrap(body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{&activityReader{reader: body, onRead: t.touch}, body}
+}
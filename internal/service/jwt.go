@@ -3,7 +3,6 @@ package service
 import (
 	"errors"
 	"fmt"
-	"slices"
 	"strings"
 	"time"
 
@@ -36,7 +35,7 @@ func NewJWT(userRepository JWTUserRepository, jwtSecret string, tokenDuration ti
 
 func (s *JWT) AuthenticateToken(token string) (model.User, error) {
 	if token == "" {
-		return model.User{}, errors.New("badparams: missing Authorization token")
+		return model.User{}, fmt.Errorf("%w: missing Authorization token", ErrUnauthenticated)
 	}
 
 	if strings.Contains(token, "Bearer") {
@@ -59,13 +58,7 @@ func (s *JWT) AuthenticateToken(token string) (model.User, error) {
 }
 
 func (s *JWT) Authorize(user model.User, requiredScopes []string) error {
-	for _, requiredScope := range requiredScopes {
-		if !slices.Contains(user.Scopes, requiredScope) {
-			return fmt.Errorf("missing %s scope", requiredScope)
-		}
-	}
-
-	return nil
+	return authorizeScopes(user, requiredScopes)
 }
 
 func (s *JWT) GenerateToken(user model.User) (string, error) {
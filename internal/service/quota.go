@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type Quota struct {
+	quotas         config.Quotas
+	store          LimiterStore
+	userRepository UserRepository
+	now            func() time.Time
+}
+
+func NewQuota(quotas config.Quotas, store LimiterStore, userRepository UserRepository) *Quota {
+	return &Quota{
+		quotas:         quotas,
+		store:          store,
+		userRepository: userRepository,
+		now:            time.Now,
+	}
+}
+
+// Consume Uses one call of every quota referenced by the backend and route, stopping at the
+// first exhausted quota, which is returned. Rejected requests do not consume any of the quotas,
+// and requests without a user are not counted
+func (q *Quota) Consume(
+	ctx context.Context,
+	backend config.Backend,
+	route config.Route,
+	user model.User,
+) (*model.QuotaUsage, error) {
+	if user.ID == "" {
+		return nil, nil
+	}
+
+	now := q.now()
+	consumed := make(map[string]time.Time)
+	for _, name := range slices.Concat(backend.Quotas, route.Quotas) {
+		quota, exists := q.quotas.Get(name)
+		if _, alreadyConsumed := consumed[name]; !exists || alreadyConsumed {
+			continue
+		}
+
+		start, end := quota.PeriodBounds(now)
+		key := q.key(quota, user.ID, start)
+
+		used, err := q.store.Increment(ctx, key, 1, end)
+		if err != nil {
+			return nil, err
+		}
+
+		consumed[name] = start
+
+		calls := q.calls(quota, user)
+		if used <= int64(calls) {
+			continue
+		}
+
+		if err := q.rollback(ctx, user, consumed); err != nil {
+			return nil, err
+		}
+
+		usage := q.usage(quota, calls, used-1, end)
+		return &usage, nil
+	}
+
+	return nil, nil
+}
+
+// rollback Undoes the increments of a rejected request, indexed by quota name and period start
+func (q *Quota) rollback(ctx context.Context, user model.User, consumed map[string]time.Time) error {
+	for name, start := range consumed {
+		quota, _ := q.quotas.Get(name)
+		_, end := quota.PeriodBounds(start)
+
+		if _, err := q.store.Increment(ctx, q.key(quota, user.ID, start), -1, end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByUser Returns the current period usage of every quota for the user
+func (q *Quota) GetByUser(ctx context.Context, userID string) ([]model.QuotaUsage, error) {
+	user, err := q.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make([]model.QuotaUsage, 0, len(q.quotas))
+	for _, quota := range q.quotas {
+		start, end := quota.PeriodBounds(q.now())
+
+		used, err := q.store.Get(ctx, q.key(quota, user.ID, start))
+		if err != nil {
+			return nil, err
+		}
+
+		usages = append(usages, q.usage(quota, q.calls(quota, user), used, end))
+	}
+
+	return usages, nil
+}
+
+// Reset Restores the current period calls of the user quota
+func (q *Quota) Reset(ctx context.Context, userID, name string) error {
+	user, err := q.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	quota, exists := q.quotas.Get(name)
+	if !exists {
+		return fmt.Errorf("quota %s %w", name, ErrNotFound)
+	}
+
+	start, _ := quota.PeriodBounds(q.now())
+
+	return q.store.Delete(ctx, q.key(quota, user.ID, start))
+}
+
+// ResetAll Restores the current period calls of every user quota
+func (q *Quota) ResetAll(ctx context.Context, userID string) error {
+	user, err := q.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range q.quotas {
+		start, _ := quota.PeriodBounds(q.now())
+
+		if err := q.store.Delete(ctx, q.key(quota, user.ID, start)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *Quota) getUser(userID string) (model.User, error) {
+	if strings.TrimSpace(userID) == "" {
+		return model.User{}, newValidationError("userId parameter must be present and must not be blank")
+	}
+
+	user, err := q.userRepository.GetByID(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return *user, nil
+}
+
+func (*Quota) key(quota config.Quota, userID string, periodStart time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%d", quota.Name, userID, periodStart.Unix())
+}
+
+// calls Returns the quota calls, or the user override from its properties if present
+func (*Quota) calls(quota config.Quota, user model.User) int {
+	if quota.UserOverrideProperty == "" {
+		return quota.Calls
+	}
+
+	if calls, err := strconv.Atoi(user.Properties[quota.UserOverrideProperty]); err == nil && calls > 0 {
+		return calls
+	}
+
+	return quota.Calls
+}
+
+func (*Quota) usage(quota config.Quota, calls int, used int64, periodEnd time.Time) model.QuotaUsage {
+	return model.QuotaUsage{
+		Name:      quota.Name,
+		Period:    string(quota.Period),
+		Limit:     calls,
+		Used:      int(used),
+		Remaining: max(calls-int(used), 0),
+		ResetsAt:  periodEnd,
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+// fakeUserRepository Is a read only user repository backed by a map
+type fakeUserRepository struct {
+	users map[string]model.User
+}
+
+func (r fakeUserRepository) GetAll() ([]model.User, error) {
+	users := make([]model.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (r fakeUserRepository) GetByID(id string) (*model.User, error) {
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	return &user, nil
+}
+
+func (r fakeUserRepository) GetByLogin(login string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Login == login {
+			return &user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("user %w", ErrNotFound)
+}
+
+func (fakeUserRepository) Create(model.CreateUserParams) (*model.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeUserRepository) Update(model.UpdateUserParams) (*model.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeUserRepository) Delete(string) error {
+	return errors.New("not implemented")
+}
+
+var quotaTestNow = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestQuota(store LimiterStore, quotas config.Quotas, users ...model.User) *Quota {
+	repository := fakeUserRepository{users: make(map[string]model.User)}
+	for _, user := range users {
+		repository.users[user.ID] = user
+	}
+
+	quota := NewQuota(quotas, store, repository)
+	quota.now = func() time.Time { return quotaTestNow }
+
+	return quota
+}
+
+func consumeTestQuota(t *testing.T, quota *Quota, backend config.Backend, route config.Route, user model.User) *model.QuotaUsage {
+	t.Helper()
+
+	usage, err := quota.Consume(context.Background(), backend, route, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return usage
+}
+
+func TestQuotaConsumeRejectsOverTheAllowance(t *testing.T) {
+	store := newFakeLimiterStore()
+	user := model.User{ID: "user-id"}
+	quota := newTestQuota(store, config.Quotas{{Name: "daily", Period: config.QuotaPeriodDay, Calls: 2}}, user)
+	backend := config.Backend{Quotas: []string{"daily"}}
+
+	for i := range 2 {
+		if usage := consumeTestQuota(t, quota, backend, config.Route{}, user); usage != nil {
+			t.Fatalf("call %d: expected allowed, got %+v", i, usage)
+		}
+	}
+
+	usage := consumeTestQuota(t, quota, backend, config.Route{}, user)
+	if usage == nil {
+		t.Fatal("expected the third call to be rejected")
+	}
+
+	wantResetsAt := time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	if usage.Name != "daily" || usage.Limit != 2 || usage.Used != 2 || usage.Remaining != 0 || !usage.ResetsAt.Equal(wantResetsAt) {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	key := fmt.Sprintf("quota:daily:user-id:%d", time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC).Unix())
+	if got := store.counters[key]; got != 2 {
+		t.Errorf("expected the rejected call to not be counted, got %d", got)
+	}
+}
+
+func TestQuotaConsumeRollsBackEarlierQuotas(t *testing.T) {
+	store := newFakeLimiterStore()
+	user := model.User{ID: "user-id"}
+	quota := newTestQuota(store, config.Quotas{
+		{Name: "monthly", Period: config.QuotaPeriodMonth, Calls: 100},
+		{Name: "daily", Period: config.QuotaPeriodDay, Calls: 1},
+	}, user)
+	backend := config.Backend{Quotas: []string{"monthly"}}
+	route := config.Route{Quotas: []string{"daily", "monthly"}}
+
+	if usage := consumeTestQuota(t, quota, backend, route, user); usage != nil {
+		t.Fatalf("expected the first call to be allowed, got %+v", usage)
+	}
+
+	if usage := consumeTestQuota(t, quota, backend, route, user); usage == nil || usage.Name != "daily" {
+		t.Fatalf("expected the daily quota to reject the second call, got %+v", usage)
+	}
+
+	monthlyKey := fmt.Sprintf("quota:monthly:user-id:%d", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Unix())
+	if got := store.counters[monthlyKey]; got != 1 {
+		t.Errorf("expected only the allowed call in the monthly quota, got %d", got)
+	}
+}
+
+func TestQuotaConsumeIgnoresAnonymousRequests(t *testing.T) {
+	store := newFakeLimiterStore()
+	quota := newTestQuota(store, config.Quotas{{Name: "daily", Period: config.QuotaPeriodDay, Calls: 1}})
+
+	for range 3 {
+		if usage := consumeTestQuota(t, quota, config.Backend{Quotas: []string{"daily"}}, config.Route{}, model.User{}); usage != nil {
+			t.Fatalf("expected anonymous requests to not be counted, got %+v", usage)
+		}
+	}
+
+	if len(store.counters) != 0 {
+		t.Errorf("expected no counters, got %v", store.counters)
+	}
+}
+
+func TestQuotaCallsUserOverride(t *testing.T) {
+	quota := newTestQuota(newFakeLimiterStore(), nil)
+	definition := config.Quota{Calls: 10, UserOverrideProperty: "monthlyCalls"}
+
+	tests := []struct {
+		name       string
+		properties map[string]string
+		want       int
+	}{
+		{"without property", nil, 10},
+		{"with override", map[string]string{"monthlyCalls": "5000"}, 5000},
+		{"with invalid override", map[string]string{"monthlyCalls": "unlimited"}, 10},
+		{"with negative override", map[string]string{"monthlyCalls": "-1"}, 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := quota.calls(definition, model.User{Properties: test.properties}); got != test.want {
+				t.Errorf("expected %d calls, got %d", test.want, got)
+			}
+		})
+	}
+}
+
+func TestQuotaGetByUserAndReset(t *testing.T) {
+	store := newFakeLimiterStore()
+	user := model.User{ID: "user-id", Properties: map[string]string{"dailyCalls": "5"}}
+	quota := newTestQuota(store, config.Quotas{
+		{Name: "daily", Period: config.QuotaPeriodDay, Calls: 2, UserOverrideProperty: "dailyCalls"},
+		{Name: "monthly", Period: config.QuotaPeriodMonth, Calls: 100},
+	}, user)
+	backend := config.Backend{Quotas: []string{"daily", "monthly"}}
+	ctx := context.Background()
+
+	consumeTestQuota(t, quota, backend, config.Route{}, user)
+	consumeTestQuota(t, quota, backend, config.Route{}, user)
+
+	usages, err := quota.GetByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(usages) != 2 || usages[0].Used != 2 || usages[0].Limit != 5 || usages[0].Remaining != 3 || usages[1].Used != 2 {
+		t.Fatalf("unexpected usages %+v", usages)
+	}
+
+	if err := quota.Reset(ctx, user.ID, "daily"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	usages, _ = quota.GetByUser(ctx, user.ID)
+	if usages[0].Used != 0 || usages[1].Used != 2 {
+		t.Fatalf("expected only the daily quota to be reset, got %+v", usages)
+	}
+
+	if err := quota.ResetAll(ctx, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	usages, _ = quota.GetByUser(ctx, user.ID)
+	if usages[0].Used != 0 || usages[1].Used != 0 {
+		t.Fatalf("expected every quota to be reset, got %+v", usages)
+	}
+}
+
+func TestQuotaResetErrors(t *testing.T) {
+	user := model.User{ID: "user-id"}
+	quota := newTestQuota(newFakeLimiterStore(), config.Quotas{{Name: "daily", Period: config.QuotaPeriodDay, Calls: 1}}, user)
+	ctx := context.Background()
+
+	if err := quota.Reset(ctx, user.ID, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found for an unknown quota, got %v", err)
+	}
+
+	if err := quota.Reset(ctx, "unknown", "daily"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found for an unknown user, got %v", err)
+	}
+
+	var validationErr *ValidationError
+	if _, err := quota.GetByUser(ctx, " "); !errors.As(err, &validationErr) {
+		t.Errorf("expected a validation error for a blank user id, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+// LimiterStore Holds the rate limit and quota counters, the implementations must be safe for
+// concurrent use. Stores shared by every gatekeeper instance enforce the limits across all of them
+type LimiterStore interface {
+	// Increment Atomically adds the delta to the key counter and returns its new value, a missing
+	// or expired counter starts from zero and expires at expiresAt
+	Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error)
+
+	// Get Returns the key counter value, missing or expired counters are zero
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Delete Removes the key counter, resetting it to zero
+	Delete(ctx context.Context, key string) error
+}
+
+type RateLimiter struct {
+	store LimiterStore
+	now   func() time.Time
+}
+
+func NewRateLimiter(store LimiterStore) *RateLimiter {
+	return &RateLimiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// rateLimitCounter Is a counter incremented by a request, kept to undo the increment if the
+// request is rejected
+type rateLimitCounter struct {
+	key       string
+	expiresAt time.Time
+}
+
+// AllowClient Consumes one request from every backend and route limit keyed by the client IP or
+// a header. It runs before the authentication, so requests with invalid credentials are limited too
+func (l *RateLimiter) AllowClient(
+	ctx context.Context,
+	backend config.Backend,
+	route config.Route,
+	clientIP string,
+	headers http.Header,
+) (*model.RateLimitStatus, error) {
+	return l.allowLimits(ctx, backend, route, model.User{}, clientIP, headers, config.RateLimit.IsClientKeyed)
+}
+
+// AllowUser Consumes one request from every backend and route limit keyed by user, requests
+// without an authenticated user are limited by their client IP
+func (l *RateLimiter) AllowUser(
+	ctx context.Context,
+	backend config.Backend,
+	route config.Route,
+	user model.User,
+	clientIP string,
+	headers http.Header,
+) (*model.RateLimitStatus, error) {
+	return l.allowLimits(ctx, backend, route, user, clientIP, headers, func(limit config.RateLimit) bool {
+		return !limit.IsClientKeyed()
+	})
+}
+
+// allowLimits Consumes one request from every included limit, stopping at the first exceeded
+// one. The returned status is the exceeded limit or, if the request is allowed, the one with the
+// fewest remaining requests. It is nil if there are no included limits. Rejected requests do not
+// consume any of the included limits
+func (l *RateLimiter) allowLimits(
+	ctx context.Context,
+	backend config.Backend,
+	route config.Route,
+	user model.User,
+	clientIP string,
+	headers http.Header,
+	include func(config.RateLimit) bool,
+) (*model.RateLimitStatus, error) {
+	var status *model.RateLimitStatus
+
+	limitGroups := []struct {
+		scope  string
+		limits config.RateLimits
+	}{
+		{scope: backend.Name, limits: backend.RateLimits},
+		{scope: backend.Name + "/" + route.Name(), limits: route.RateLimits},
+	}
+
+	counters := make([]rateLimitCounter, 0)
+	for _, group := range limitGroups {
+		for _, limit := range group.limits {
+			if !include(limit) {
+				continue
+			}
+
+			limitStatus, counter, err := l.allow(ctx, group.scope, limit, user, clientIP, headers)
+			if err != nil {
+				return nil, err
+			}
+
+			counters = append(counters, counter)
+
+			if !limitStatus.Allowed {
+				if err := l.rollback(ctx, counters); err != nil {
+					return nil, err
+				}
+
+				return &limitStatus, nil
+			}
+
+			if status == nil || limitStatus.Remaining < status.Remaining {
+				status = &limitStatus
+			}
+		}
+	}
+
+	return status, nil
+}
+
+func (l *RateLimiter) allow(
+	ctx context.Context,
+	scope string,
+	limit config.RateLimit,
+	user model.User,
+	clientIP string,
+	headers http.Header,
+) (model.RateLimitStatus, rateLimitCounter, error) {
+	window := limit.Window()
+	now := l.now()
+	windowStart := now.Truncate(window)
+	keyPrefix := fmt.Sprintf("ratelimit:%s:%s:%s", scope, limit.Name, l.keyValue(limit, user, clientIP, headers))
+	counter := rateLimitCounter{
+		key: fmt.Sprintf("%s:%d", keyPrefix, windowStart.Unix()),
+		// The counter is kept for one more window, as it is the previous window of the sliding estimate
+		expiresAt: windowStart.Add(2 * window),
+	}
+
+	count, err := l.store.Increment(ctx, counter.key, 1, counter.expiresAt)
+	if err != nil {
+		return model.RateLimitStatus{}, counter, err
+	}
+
+	estimated := count
+	if limit.Algorithm == config.RateLimitSlidingWindow {
+		previousKey := fmt.Sprintf("%s:%d", keyPrefix, windowStart.Add(-window).Unix())
+
+		previousCount, err := l.store.Get(ctx, previousKey)
+		if err != nil {
+			return model.RateLimitStatus{}, counter, err
+		}
+
+		previousWeight := 1 - float64(now.Sub(windowStart))/float64(window)
+		estimated += int64(math.Floor(float64(previousCount) * previousWeight))
+	}
+
+	requests := l.requests(limit, user)
+	status := model.RateLimitStatus{
+		Name:      limit.Name,
+		Allowed:   estimated <= int64(requests),
+		Limit:     requests,
+		Remaining: max(requests-int(estimated), 0),
+		Window:    window,
+		Reset:     windowStart.Add(window).Sub(now),
+	}
+
+	return status, counter, nil
+}
+
+// rollback Undoes the increments of a rejected request
+func (l *RateLimiter) rollback(ctx context.Context, counters []rateLimitCounter) error {
+	for _, counter := range counters {
+		if _, err := l.store.Increment(ctx, counter.key, -1, counter.expiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyValue Returns who the limit is applied to, requests without the limit key, like the ones to
+// public routes when limiting by user, are limited by their client IP
+func (*RateLimiter) keyValue(limit config.RateLimit, user model.User, clientIP string, headers http.Header) string {
+	switch limit.KeyBy {
+	case config.RateLimitKeyUser:
+		if user.ID != "" {
+			return "user:" + user.ID
+		}
+	case config.RateLimitKeyHeader, config.RateLimitKeyAPIKey:
+		// Header values may be secrets, like API keys, so only their hashes are stored
+		if value := headers.Get(limit.Header); value != "" {
+			hash := sha256.Sum256([]byte(value))
+			return "header:" + hex.EncodeToString(hash[:16])
+		}
+	}
+
+	return "ip:" + clientIP
+}
+
+// requests Returns the limit requests, or the user override from its properties if present
+func (*RateLimiter) requests(limit config.RateLimit, user model.User) int {
+	if limit.UserOverrideProperty == "" {
+		return limit.Requests
+	}
+
+	if requests, err := strconv.Atoi(user.Properties[limit.UserOverrideProperty]); err == nil && requests > 0 {
+		return requests
+	}
+
+	return limit.Requests
+}
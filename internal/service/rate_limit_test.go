@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+// fakeLimiterStore Is a limiter store without expiration, so the tests can use any clock
+type fakeLimiterStore struct {
+	counters map[string]int64
+}
+
+func newFakeLimiterStore() *fakeLimiterStore {
+	return &fakeLimiterStore{counters: make(map[string]int64)}
+}
+
+func (s *fakeLimiterStore) Increment(_ context.Context, key string, delta int64, _ time.Time) (int64, error) {
+	s.counters[key] += delta
+	return s.counters[key], nil
+}
+
+func (s *fakeLimiterStore) Get(_ context.Context, key string) (int64, error) {
+	return s.counters[key], nil
+}
+
+func (s *fakeLimiterStore) Delete(_ context.Context, key string) error {
+	delete(s.counters, key)
+	return nil
+}
+
+func newTestRateLimiter(store LimiterStore, now time.Time) *RateLimiter {
+	limiter := NewRateLimiter(store)
+	limiter.now = func() time.Time { return now }
+
+	return limiter
+}
+
+func newTestRateLimit(limit config.RateLimit) config.RateLimit {
+	limit.Normalize(0)
+	return limit
+}
+
+func TestRateLimiterSlidingWindowWeightsThePreviousWindow(t *testing.T) {
+	store := newFakeLimiterStore()
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestRateLimiter(store, windowStart.Add(15*time.Second))
+
+	backend := config.Backend{
+		Name:       "backend",
+		RateLimits: config.RateLimits{newTestRateLimit(config.RateLimit{Name: "limit", Requests: 10, WindowSeconds: 60})},
+	}
+	user := model.User{ID: "user-id"}
+
+	// A quarter of the window has passed, so 3/4 of the 8 previous requests are estimated
+	previousKey := fmt.Sprintf("ratelimit:backend:limit:user:user-id:%d", windowStart.Add(-time.Minute).Unix())
+	store.counters[previousKey] = 8
+
+	for i, expectedRemaining := range []int{3, 2, 1, 0} {
+		status, err := limiter.AllowUser(context.Background(), backend, config.Route{}, user, "", http.Header{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !status.Allowed || status.Remaining != expectedRemaining {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i, expectedRemaining, status)
+		}
+	}
+
+	status, err := limiter.AllowUser(context.Background(), backend, config.Route{}, user, "", http.Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status.Allowed || status.Reset != 45*time.Second {
+		t.Errorf("expected rejected with a 45s reset, got %+v", status)
+	}
+
+	currentKey := fmt.Sprintf("ratelimit:backend:limit:user:user-id:%d", windowStart.Unix())
+	if got := store.counters[currentKey]; got != 4 {
+		t.Errorf("expected the rejected request to not be counted, got %d", got)
+	}
+}
+
+func TestRateLimiterFixedWindowIgnoresThePreviousWindow(t *testing.T) {
+	store := newFakeLimiterStore()
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestRateLimiter(store, windowStart.Add(time.Second))
+
+	limit := newTestRateLimit(config.RateLimit{
+		Name:          "limit",
+		Algorithm:     config.RateLimitFixedWindow,
+		Requests:      1,
+		WindowSeconds: 60,
+	})
+	store.counters[fmt.Sprintf("ratelimit:backend:limit:user:user-id:%d", windowStart.Add(-time.Minute).Unix())] = 100
+
+	status, err := limiter.AllowUser(
+		context.Background(),
+		config.Backend{Name: "backend", RateLimits: config.RateLimits{limit}},
+		config.Route{},
+		model.User{ID: "user-id"},
+		"",
+		http.Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !status.Allowed {
+		t.Errorf("expected the previous window to be ignored, got %+v", status)
+	}
+}
+
+func TestRateLimiterKeyValue(t *testing.T) {
+	limiter := NewRateLimiter(newFakeLimiterStore())
+	user := model.User{ID: "user-id"}
+	headers := http.Header{"X-Api-Key": {"secret-key"}}
+
+	tests := []struct {
+		name    string
+		limit   config.RateLimit
+		user    model.User
+		headers http.Header
+		want    string
+	}{
+		{"user", config.RateLimit{KeyBy: config.RateLimitKeyUser}, user, headers, "user:user-id"},
+		{"user falls back to ip", config.RateLimit{KeyBy: config.RateLimitKeyUser}, model.User{}, headers, "ip:203.0.113.7"},
+		{"ip", config.RateLimit{KeyBy: config.RateLimitKeyIP}, user, headers, "ip:203.0.113.7"},
+		{"api key falls back to ip", config.RateLimit{KeyBy: config.RateLimitKeyAPIKey}, user, http.Header{}, "ip:203.0.113.7"},
+		{"header falls back to ip", config.RateLimit{KeyBy: config.RateLimitKeyHeader, Header: "X-Tenant"}, user, headers, "ip:203.0.113.7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limit := newTestRateLimit(test.limit)
+			if got := limiter.keyValue(limit, test.user, "203.0.113.7", test.headers); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+
+	limit := newTestRateLimit(config.RateLimit{KeyBy: config.RateLimitKeyAPIKey})
+	got := limiter.keyValue(limit, user, "203.0.113.7", headers)
+	if !strings.HasPrefix(got, "header:") || strings.Contains(got, "secret-key") {
+		t.Errorf("expected a hashed header key, got %q", got)
+	}
+
+	if other := limiter.keyValue(limit, user, "203.0.113.7", http.Header{"X-Api-Key": {"other-key"}}); other == got {
+		t.Errorf("expected different keys for different header values, got %q", other)
+	}
+}
+
+func TestRateLimiterRequestsUserOverride(t *testing.T) {
+	limiter := NewRateLimiter(newFakeLimiterStore())
+	limit := config.RateLimit{Requests: 10, UserOverrideProperty: "requestsPerMinute"}
+
+	tests := []struct {
+		name       string
+		properties map[string]string
+		want       int
+	}{
+		{"without property", nil, 10},
+		{"with override", map[string]string{"requestsPerMinute": "50"}, 50},
+		{"with invalid override", map[string]string{"requestsPerMinute": "many"}, 10},
+		{"with non positive override", map[string]string{"requestsPerMinute": "0"}, 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := limiter.requests(limit, model.User{Properties: test.properties}); got != test.want {
+				t.Errorf("expected %d requests, got %d", test.want, got)
+			}
+		})
+	}
+
+	if got := limiter.requests(config.RateLimit{Requests: 10}, model.User{Properties: map[string]string{"requestsPerMinute": "50"}}); got != 10 {
+		t.Errorf("expected the override to be ignored without the property config, got %d", got)
+	}
+}
+
+func TestRateLimiterRejectedRequestDoesNotConsumeOtherLimits(t *testing.T) {
+	store := newFakeLimiterStore()
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestRateLimiter(store, windowStart)
+
+	backend := config.Backend{
+		Name: "backend",
+		RateLimits: config.RateLimits{
+			newTestRateLimit(config.RateLimit{Name: "backend-limit", Requests: 10, WindowSeconds: 60}),
+		},
+	}
+	route := config.Route{
+		Method:         http.MethodGet,
+		GatekeeperPath: "/items",
+		RateLimits: config.RateLimits{
+			newTestRateLimit(config.RateLimit{Name: "route-limit", Requests: 1, WindowSeconds: 60}),
+		},
+	}
+	user := model.User{ID: "user-id"}
+
+	for i, expectedAllowed := range []bool{true, false, false} {
+		status, err := limiter.AllowUser(context.Background(), backend, route, user, "", http.Header{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if status.Allowed != expectedAllowed {
+			t.Fatalf("request %d: expected allowed=%t, got %+v", i, expectedAllowed, status)
+		}
+
+		if !expectedAllowed && status.Name != "route-limit" {
+			t.Errorf("request %d: expected the route limit to reject, got %s", i, status.Name)
+		}
+	}
+
+	backendKey := fmt.Sprintf("ratelimit:backend:backend-limit:user:user-id:%d", windowStart.Unix())
+	if got := store.counters[backendKey]; got != 1 {
+		t.Errorf("expected only the allowed request in the backend limit, got %d", got)
+	}
+
+	routeKey := fmt.Sprintf("ratelimit:backend/%s:route-limit:user:user-id:%d", route.Name(), windowStart.Unix())
+	if got := store.counters[routeKey]; got != 1 {
+		t.Errorf("expected only the allowed request in the route limit, got %d", got)
+	}
+}
+
+func TestRateLimiterSplitsClientAndUserLimits(t *testing.T) {
+	store := newFakeLimiterStore()
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestRateLimiter(store, windowStart)
+
+	backend := config.Backend{
+		Name: "backend",
+		RateLimits: config.RateLimits{
+			newTestRateLimit(config.RateLimit{Name: "per-user", Requests: 10, WindowSeconds: 60}),
+			newTestRateLimit(config.RateLimit{Name: "per-ip", KeyBy: config.RateLimitKeyIP, Requests: 2, WindowSeconds: 60}),
+			newTestRateLimit(config.RateLimit{Name: "per-key", KeyBy: config.RateLimitKeyAPIKey, Requests: 5, WindowSeconds: 60}),
+		},
+	}
+	headers := http.Header{"X-Api-Key": {"secret-key"}}
+
+	// The client limits are checked before the authentication, so there is no user yet
+	for i, expectedAllowed := range []bool{true, true, false} {
+		status, err := limiter.AllowClient(context.Background(), backend, config.Route{}, "203.0.113.7", headers)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if status.Allowed != expectedAllowed {
+			t.Fatalf("request %d: expected allowed=%t, got %+v", i, expectedAllowed, status)
+		}
+	}
+
+	userKey := fmt.Sprintf("ratelimit:backend:per-user:user:user-id:%d", windowStart.Unix())
+	if got := store.counters[userKey]; got != 0 {
+		t.Errorf("expected the user limit to not be checked before the authentication, got %d", got)
+	}
+
+	status, err := limiter.AllowUser(context.Background(), backend, config.Route{}, model.User{ID: "user-id"}, "203.0.113.7", headers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !status.Allowed || status.Name != "per-user" {
+		t.Errorf("expected only the user limit to be checked after the authentication, got %+v", status)
+	}
+
+	ipKey := fmt.Sprintf("ratelimit:backend:per-ip:ip:203.0.113.7:%d", windowStart.Unix())
+	if got := store.counters[ipKey]; got != 2 {
+		t.Errorf("expected only the allowed client requests in the ip limit, got %d", got)
+	}
+}
+
+func TestRateLimiterWithoutIncludedLimits(t *testing.T) {
+	limiter := NewRateLimiter(newFakeLimiterStore())
+	backend := config.Backend{
+		Name:       "backend",
+		RateLimits: config.RateLimits{newTestRateLimit(config.RateLimit{Requests: 1, WindowSeconds: 60})},
+	}
+
+	status, err := limiter.AllowClient(context.Background(), backend, config.Route{}, "203.0.113.7", http.Header{})
+	if err != nil || status != nil {
+		t.Errorf("expected no status without client limits, got %+v, %v", status, err)
+	}
+}
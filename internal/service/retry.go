@@ -0,0 +1,175 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// replayableBody Buffers up to a limit of the request body so it can be sent again on retries.
+// Bodies larger than the limit are streamed once and cannot be replayed
+type replayableBody struct {
+	buffer     []byte
+	rest       io.ReadCloser
+	replayable bool
+}
+
+func newReplayableBody(body io.ReadCloser, limit int) (*replayableBody, error) {
+	if body == nil || body == http.NoBody {
+		return &replayableBody{replayable: true}, nil
+	}
+
+	buffer, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(buffer) <= limit {
+		body.Close()
+		return &replayableBody{buffer: buffer, replayable: true}, nil
+	}
+
+	return &replayableBody{buffer: buffer, rest: body}, nil
+}
+
+// contentLength Returns the length of the buffered body, bodies that were not fully buffered
+// have the length announced by the client, -1 if unknown
+func (b *replayableBody) contentLength(clientContentLength int64) int64 {
+	if b.replayable {
+		return int64(len(b.buffer))
+	}
+
+	return clientContentLength
+}
+
+func (b *replayableBody) reader() io.ReadCloser {
+	if b.rest != nil && len(b.buffer) == 0 {
+		return b.rest
+	}
+
+	if b.rest != nil {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b.buffer), b.rest), b.rest}
+	}
+
+	if len(b.buffer) == 0 {
+		return http.NoBody
+	}
+
+	return io.NopCloser(bytes.NewReader(b.buffer))
+}
+
+// retryPolicy Decides if a failed attempt can be retried, following the route retry config
+type retryPolicy struct {
+	cfg         config.Retry
+	maxAttempts int
+}
+
+func newRetryPolicy(cfg config.Retry, method string) retryPolicy {
+	maxAttempts := 1
+	if cfg.IsEnabled() && (cfg.RetryNonIdempotent || slices.Contains(idempotentMethods, method)) {
+		maxAttempts = cfg.MaxAttempts
+	}
+
+	return retryPolicy{
+		cfg:         cfg,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (p retryPolicy) isEnabled() bool {
+	return p.maxAttempts > 1
+}
+
+func (p retryPolicy) shouldRetry(attempt int, response *http.Response, err error) bool {
+	if attempt >= p.maxAttempts {
+		return false
+	}
+
+	if err != nil {
+		return slices.Contains(p.cfg.RetryOnErrors, classifyUpstreamError(err))
+	}
+
+	return slices.Contains(p.cfg.RetryOnStatuses, response.StatusCode)
+}
+
+// backoff Returns the exponential backoff of the attempt, with full jitter
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.cfg.InitialBackoff() << (attempt - 1)
+	if backoff <= 0 || backoff > p.cfg.MaxBackoff() {
+		backoff = p.cfg.MaxBackoff()
+	}
+
+	return time.Duration(rand.Int64N(int64(backoff) + 1))
+}
+
+// wait Sleeps for the attempt backoff, returning false if the context is done or its deadline
+// would be reached before the backoff ends
+func (p retryPolicy) wait(ctx context.Context, attempt int) bool {
+	backoff := p.backoff(attempt)
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+		return false
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// classifyUpstreamError Returns the retry error class of an upstream request error
+func classifyUpstreamError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return config.RetryOnTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return config.RetryOnConnect
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return config.RetryOnConnect
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return config.RetryOnReset
+	}
+
+	return ""
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyUpstreamError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"deadline exceeded", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), config.RetryOnTimeout},
+		{"network timeout", &net.OpError{Op: "read", Err: timeoutError{}}, config.RetryOnTimeout},
+		{"dns", &net.DNSError{Err: "no such host", Name: "backend"}, config.RetryOnConnect},
+		{"connection refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, config.RetryOnConnect},
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("network is unreachable")}, config.RetryOnConnect},
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, config.RetryOnReset},
+		{"broken pipe", &net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}, config.RetryOnReset},
+		{"eof", fmt.Errorf("reading response: %w", io.EOF), config.RetryOnReset},
+		{"unexpected eof", io.ErrUnexpectedEOF, config.RetryOnReset},
+		{"other", errors.New("tls: bad certificate"), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classifyUpstreamError(test.err); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	cfg := config.Retry{
+		MaxAttempts:     3,
+		RetryOnStatuses: []int{http.StatusServiceUnavailable},
+		RetryOnErrors:   []string{config.RetryOnConnect},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Retry
+		method   string
+		attempt  int
+		response *http.Response
+		err      error
+		want     bool
+	}{
+		{"retryable status", cfg, http.MethodGet, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"other status", cfg, http.MethodGet, 1, &http.Response{StatusCode: http.StatusBadGateway}, nil, false},
+		{"success", cfg, http.MethodGet, 1, &http.Response{StatusCode: http.StatusOK}, nil, false},
+		{"retryable error", cfg, http.MethodGet, 2, nil, &net.DNSError{Name: "backend"}, true},
+		{"other error class", cfg, http.MethodGet, 1, nil, io.EOF, false},
+		{"attempts exhausted", cfg, http.MethodGet, 3, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, false},
+		{"idempotent put", cfg, http.MethodPut, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"non idempotent post", cfg, http.MethodPost, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, false},
+		{
+			"non idempotent post allowed",
+			config.Retry{MaxAttempts: 3, RetryOnStatuses: cfg.RetryOnStatuses, RetryNonIdempotent: true},
+			http.MethodPost, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true,
+		},
+		{"disabled", config.Retry{MaxAttempts: 1, RetryOnStatuses: cfg.RetryOnStatuses}, http.MethodGet, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := newRetryPolicy(test.cfg, test.method)
+			if got := policy.shouldRetry(test.attempt, test.response, test.err); got != test.want {
+				t.Errorf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := newRetryPolicy(config.Retry{
+		MaxAttempts:                10,
+		InitialBackoffMilliseconds: 100,
+		MaxBackoffMilliseconds:     1000,
+	}, http.MethodGet)
+
+	for attempt, limit := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		5:  time.Second,
+		70: time.Second,
+	} {
+		for range 50 {
+			if backoff := policy.backoff(attempt); backoff < 0 || backoff > limit {
+				t.Fatalf("expected the backoff of attempt %d to be within [0, %s], got %s", attempt, limit, backoff)
+			}
+		}
+	}
+}
+
+func TestReplayableBody(t *testing.T) {
+	body, err := newReplayableBody(io.NopCloser(strings.NewReader("hello")), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !body.replayable {
+		t.Fatalf("expected a body within the limit to be replayable")
+	}
+
+	for range 2 {
+		if got, _ := io.ReadAll(body.reader()); string(got) != "hello" {
+			t.Errorf("expected the body to be replayed, got %q", got)
+		}
+	}
+
+	large, err := newReplayableBody(io.NopCloser(strings.NewReader("hello world")), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if large.replayable {
+		t.Fatalf("expected a body over the limit to not be replayable")
+	}
+
+	if got, _ := io.ReadAll(large.reader()); string(got) != "hello world" {
+		t.Errorf("expected the whole body to be streamed once, got %q", got)
+	}
+
+	empty, err := newReplayableBody(http.NoBody, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !empty.replayable || empty.reader() != http.NoBody {
+		t.Errorf("expected an empty body to be replayable as http.NoBody")
+	}
+}
+
+func TestDoRequestToBackendRouteRetriesWithReplayedBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"replayable body", "payload", 2, http.StatusOK},
+		{"body over the buffer", strings.Repeat("x", 32), 1, http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				mu.Lock()
+				received = append(received, string(body))
+				attempt := len(received)
+				mu.Unlock()
+
+				if attempt == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			backend := config.Backend{Host: server.URL}
+			route := config.Route{
+				Method:      http.MethodPut,
+				BackendPath: "/items",
+				Retry: config.Retry{
+					MaxAttempts:                3,
+					RetryOnStatuses:            []int{http.StatusServiceUnavailable},
+					InitialBackoffMilliseconds: 1,
+					MaxBackoffMilliseconds:     1,
+					BodyBufferBytes:            16,
+				},
+			}
+
+			response, err := NewBackend([]config.Backend{backend}, nil, "").DoRequestToBackendRoute(
+				context.Background(), backend, route, model.BackendRequestParams{
+					Body:        io.NopCloser(bytes.NewBufferString(test.body)),
+					Headers:     http.Header{},
+					QueryParams: url.Values{},
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+
+			if response.StatusCode != test.wantStatus {
+				t.Errorf("expected status %d, got %d", test.wantStatus, response.StatusCode)
+			}
+
+			if len(received) != test.wantAttempts {
+				t.Fatalf("expected %d attempts, got %d", test.wantAttempts, len(received))
+			}
+
+			for i, body := range received {
+				if body != test.body {
+					t.Errorf("expected attempt %d to receive the whole body, got %q", i+1, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type MissingScopesError struct {
+	Scopes []string
+}
+
+func (e *MissingScopesError) Error() string {
+	return fmt.Sprintf("missing %s scopes", strings.Join(e.Scopes, ", "))
+}
+
+// authorizeScopes Checks if the user has every required scope, returning a MissingScopesError
+// with all the missing ones otherwise
+func authorizeScopes(user model.User, requiredScopes []string) error {
+	var missingScopes []string
+	for _, requiredScope := range requiredScopes {
+		if !slices.Contains(user.Scopes, requiredScope) {
+			missingScopes = append(missingScopes, requiredScope)
+		}
+	}
+
+	if len(missingScopes) > 0 {
+		return &MissingScopesError{Scopes: missingScopes}
+	}
+
+	return nil
+}
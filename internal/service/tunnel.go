@@ -0,0 +1,78 @@
+package service
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+)
+
+type TunnelStats struct {
+	BytesFromClient int64
+	BytesToClient   int64
+	Duration        time.Duration
+}
+
+// activityReader Calls onRead after every successful read, used to track the tunnel idleness
+type activityReader struct {
+	reader io.Reader
+	onRead func()
+}
+
+func (r *activityReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.onRead()
+	}
+
+	return n, err
+}
+
+// Tunnel Pipes bytes in both directions between the client and the upstream connections of an
+// upgraded request, until one of the sides closes or no bytes are exchanged for the idle timeout.
+// The clientReader must be used to read from the client, as it may hold already buffered bytes
+func (b *Backend) Tunnel(
+	backend config.Backend,
+	client io.ReadWriteCloser,
+	clientReader io.Reader,
+	upstream io.ReadWriteCloser,
+	idleTimeout time.Duration,
+) TunnelStats {
+	backendUpstream := b.upstreams.get(backend)
+	backendUpstream.activeTunnels.Add(1)
+	defer backendUpstream.activeTunnels.Add(-1)
+
+	start := time.Now()
+	closeBoth := sync.OnceFunc(func() {
+		client.Close()
+		upstream.Close()
+	})
+
+	idle := newIdleTimeout(idleTimeout, closeBoth)
+	defer idle.stop()
+
+	var stats TunnelStats
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		defer closeBoth()
+
+		stats.BytesFromClient, _ = io.Copy(upstream, &activityReader{reader: clientReader, onRead: idle.touch})
+	}()
+
+	go func() {
+		defer wg.Done()
+		defer closeBoth()
+
+		stats.BytesToClient, _ = io.Copy(client, &activityReader{reader: upstream, onRead: idle.touch})
+	}()
+
+	wg.Wait()
+
+	stats.Duration = time.Since(start)
+
+	return stats
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+type upstreamTarget struct {
+	host              string
+	weight            int
+	activeConnections atomic.Int64
+	unhealthy         atomic.Bool
+	ejectedUntil      atomic.Int64
+	trafficFailures   atomic.Int64
+
+	healthMu             sync.Mutex
+	consecutiveSuccesses int
+	consecutiveFailures  int
+	lastCheckedAt        *time.Time
+	lastError            string
+}
+
+func (t *upstreamTarget) isAvailable() bool {
+	return !t.unhealthy.Load() && !t.isEjected()
+}
+
+func (t *upstreamTarget) isEjected() bool {
+	return time.Now().UnixNano() < t.ejectedUntil.Load()
+}
+
+// recordTrafficResult Passively tracks the target failures on live traffic, ejecting the target
+// from rotation after too many consecutive failures. Returns true if the target was ejected
+func (t *upstreamTarget) recordTrafficResult(cfg config.OutlierDetection, failed bool) bool {
+	if !cfg.IsEnabled() {
+		return false
+	}
+
+	if !failed {
+		t.trafficFailures.Store(0)
+		return false
+	}
+
+	if t.trafficFailures.Add(1) < int64(cfg.ConsecutiveFailures) {
+		return false
+	}
+
+	t.trafficFailures.Store(0)
+	t.ejectedUntil.Store(time.Now().Add(cfg.EjectionDuration()).UnixNano())
+
+	return true
+}
+
+// recordHealthCheck Updates the target health with a health check result, returning true if the
+// target health state changed
+func (t *upstreamTarget) recordHealthCheck(cfg config.HealthCheck, checkErr error) bool {
+	t.healthMu.Lock()
+	defer t.healthMu.Unlock()
+
+	now := time.Now()
+	t.lastCheckedAt = &now
+
+	if checkErr == nil {
+		t.lastError = ""
+		t.consecutiveFailures = 0
+		t.consecutiveSuccesses++
+
+		if t.unhealthy.Load() && t.consecutiveSuccesses >= cfg.HealthyThreshold {
+			t.unhealthy.Store(false)
+			return true
+		}
+
+		return false
+	}
+
+	t.lastError = checkErr.Error()
+	t.consecutiveSuccesses = 0
+	t.consecutiveFailures++
+
+	if !t.unhealthy.Load() && t.consecutiveFailures >= cfg.UnhealthyThreshold {
+		t.unhealthy.Store(true)
+		return true
+	}
+
+	return false
+}
+
+func (t *upstreamTarget) health() model.TargetHealth {
+	t.healthMu.Lock()
+	defer t.healthMu.Unlock()
+
+	return model.TargetHealth{
+		Host:              t.host,
+		Healthy:           t.isAvailable(),
+		ActiveConnections: t.activeConnections.Load(),
+		LastCheckedAt:     t.lastCheckedAt,
+		LastError:         t.lastError,
+	}
+}
+
+func (t *upstreamTarget) acquire() {
+	t.activeConnections.Add(1)
+}
+
+func (t *upstreamTarget) release() {
+	t.activeConnections.Add(-1)
+}
+
+type backendUpstream struct {
+	name             string
+	healthCheck      config.HealthCheck
+	outlierDetection config.OutlierDetection
+	transport        *http.Transport
+	balancer         *balancer
+	targets          []*upstreamTarget
+	stopHealthCheck  context.CancelFunc
+
+	activeTunnels atomic.Int64
+
+	breaker         *circuitBreaker
+	routeBreakersMu sync.Mutex
+	routeBreakers   map[string]*circuitBreaker
+}
+
+func newBackendUpstream(backend config.Backend) *backendUpstream {
+	targetsConfig := backend.Targets
+	if len(targetsConfig) == 0 {
+		targetsConfig = []config.Target{{Host: backend.Host, Weight: 1}}
+	}
+
+	targets := make([]*upstreamTarget, len(targetsConfig))
+	for i, target := range targetsConfig {
+		targets[i] = &upstreamTarget{
+			host:   target.Host,
+			weight: max(target.Weight, 1),
+		}
+	}
+
+	return &backendUpstream{
+		name:             backend.Name,
+		healthCheck:      backend.HealthCheck,
+		outlierDetection: backend.OutlierDetection,
+		transport:        newTransport(backend.Transport, backend.Protocol),
+		balancer:         newBalancer(backend.LoadBalancing, targets),
+		targets:          targets,
+		breaker:          newCircuitBreaker(backend.CircuitBreaker),
+		routeBreakers:    make(map[string]*circuitBreaker),
+	}
+}
+
+func (u *backendUpstream) routeBreaker(route config.Route) *circuitBreaker {
+	if !route.CircuitBreaker.IsEnabled() {
+		return nil
+	}
+
+	u.routeBreakersMu.Lock()
+	defer u.routeBreakersMu.Unlock()
+
+	breaker, exists := u.routeBreakers[route.Name()]
+	if !exists {
+		breaker = newCircuitBreaker(route.CircuitBreaker)
+		u.routeBreakers[route.Name()] = breaker
+	}
+
+	return breaker
+}
+
+func (u *backendUpstream) startHealthCheck(ctx context.Context, logger *slog.Logger) {
+	if !u.healthCheck.IsEnabled() {
+		return
+	}
+
+	ctx, u.stopHealthCheck = context.WithCancel(ctx)
+
+	checker := newHealthChecker(u, logger.With("backend", u.name))
+	go checker.run(ctx)
+}
+
+func (u *backendUpstream) health() model.BackendHealth {
+	backendHealth := model.BackendHealth{
+		Name:          u.name,
+		CircuitState:  u.breaker.currentState().String(),
+		ActiveTunnels: u.activeTunnels.Load(),
+		Targets:       make([]model.TargetHealth, len(u.targets)),
+	}
+
+	for i, target := range u.targets {
+		backendHealth.Targets[i] = target.health()
+		backendHealth.Healthy = backendHealth.Healthy || backendHealth.Targets[i].Healthy
+	}
+
+	return backendHealth
+}
+
+func (u *backendUpstream) close() {
+	if u.stopHealthCheck != nil {
+		u.stopHealthCheck()
+	}
+
+	u.transport.CloseIdleConnections()
+}
+
+func newTransport(cfg config.Transport, protocol config.BackendProtocol) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout(),
+		KeepAlive: cfg.KeepAlive(),
+	}
+
+	protocols := new(http.Protocols)
+	switch protocol {
+	case config.BackendProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case config.BackendProtocolHTTP2:
+		protocols.SetHTTP2(true)
+	case config.BackendProtocolH2C:
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+
+	return &http.Transport{
+		Protocols:             protocols,
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout(),
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout(),
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout(),
+	}
+}
+
+type upstreamPool struct {
+	mu        sync.RWMutex
+	upstreams map[string]*backendUpstream
+}
+
+func newUpstreamPool(backends []config.Backend) *upstreamPool {
+	pool := &upstreamPool{
+		upstreams: make(map[string]*backendUpstream, len(backends)),
+	}
+
+	for _, backend := range backends {
+		pool.upstreams[backend.Name] = newBackendUpstream(backend)
+	}
+
+	return pool
+}
+
+// get Returns the upstream of the backend, lazily creating one for backends that were not
+// known when the pool was created
+func (p *upstreamPool) get(backend config.Backend) *backendUpstream {
+	p.mu.RLock()
+	upstream, exists := p.upstreams[backend.Name]
+	p.mu.RUnlock()
+
+	if exists {
+		return upstream
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if upstream, exists := p.upstreams[backend.Name]; exists {
+		return upstream
+	}
+
+	upstream = newBackendUpstream(backend)
+	p.upstreams[backend.Name] = upstream
+
+	return upstream
+}
+
+// startHealthChecks Starts the health checks of every backend with the health check enabled,
+// the checks will run until the context is canceled or the pool is closed
+func (p *upstreamPool) startHealthChecks(ctx context.Context, logger *slog.Logger) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, upstream := range p.upstreams {
+		upstream.startHealthCheck(ctx, logger)
+	}
+}
+
+func (p *upstreamPool) health() []model.BackendHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	backendsHealth := make([]model.BackendHealth, 0, len(p.upstreams))
+	for _, upstream := range p.upstreams {
+		backendsHealth = append(backendsHealth, upstream.health())
+	}
+
+	slices.SortFunc(backendsHealth, func(a, b model.BackendHealth) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return backendsHealth
+}
+
+func (p *upstreamPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, upstream := range p.upstreams {
+		upstream.close()
+	}
+}
+
+// onCloseBody Calls the onClose function, only once, when the response body is closed
+type onCloseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	onClose func()
+}
+
+func (b *onCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.onClose)
+
+	return err
+}
+
+// onCloseReadWriteBody Is the onCloseBody for switched protocol responses, where the body is the
+// writable upstream connection
+type onCloseReadWriteBody struct {
+	*onCloseBody
+	writer io.Writer
+}
+
+func (b *onCloseReadWriteBody) Write(p []byte) (int, error) {
+	return b.writer.Write(p)
+}
+
+func wrapBodyOnClose(body io.ReadCloser, onClose func()) io.ReadCloser {
+	wrapped := &onCloseBody{
+		ReadCloser: body,
+		onClose:    onClose,
+	}
+
+	if writer, ok := body.(io.Writer); ok {
+		return &onCloseReadWriteBody{
+			onCloseBody: wrapped,
+			writer:      writer,
+		}
+	}
+
+	return wrapped
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+type UpstreamErrorKind string
+
+const (
+	UpstreamErrorUnavailable UpstreamErrorKind = "unavailable"
+	UpstreamErrorTimeout     UpstreamErrorKind = "timeout"
+	UpstreamErrorDNS         UpstreamErrorKind = "dns"
+	UpstreamErrorConnection  UpstreamErrorKind = "connection"
+	UpstreamErrorTLS         UpstreamErrorKind = "tls"
+	UpstreamErrorCanceled    UpstreamErrorKind = "canceled"
+	UpstreamErrorBadGateway  UpstreamErrorKind = "bad-gateway"
+)
+
+// UpstreamError Is a failed exchange with a backend, the wrapped error may contain internal
+// details, like hostnames, so only the Kind should be exposed to clients
+type UpstreamError struct {
+	Kind       UpstreamErrorKind
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return string(e.Kind) + ": " + e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode Returns the HTTP status that represents the error to the client
+func (e *UpstreamError) StatusCode() int {
+	switch e.Kind {
+	case UpstreamErrorUnavailable:
+		return http.StatusServiceUnavailable
+	case UpstreamErrorTimeout:
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+// Message Returns a client safe description of the error
+func (e *UpstreamError) Message() string {
+	switch e.Kind {
+	case UpstreamErrorUnavailable:
+		return "No healthy upstream available"
+	case UpstreamErrorTimeout:
+		return "Upstream request timed out"
+	case UpstreamErrorDNS:
+		return "Upstream host could not be resolved"
+	case UpstreamErrorConnection:
+		return "Upstream connection failed"
+	case UpstreamErrorTLS:
+		return "Upstream TLS handshake failed"
+	case UpstreamErrorCanceled:
+		return "Client closed the request"
+	}
+
+	return "Bad gateway"
+}
+
+// newUpstreamError Classifies the error of an exchange. The clientCtx is the context of the client
+// request and the requestCtx the one bounded by the route timeouts
+func newUpstreamError(clientCtx context.Context, requestCtx context.Context, err error) *UpstreamError {
+	upstreamErr := &UpstreamError{
+		Kind: UpstreamErrorBadGateway,
+		Err:  err,
+	}
+
+	var circuitOpenErr *CircuitOpenError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &circuitOpenErr):
+		upstreamErr.Kind = UpstreamErrorUnavailable
+		upstreamErr.RetryAfter = circuitOpenErr.RetryAfter
+	case errors.Is(err, ErrNoAvailableUpstream):
+		upstreamErr.Kind = UpstreamErrorUnavailable
+	case clientCtx.Err() != nil:
+		upstreamErr.Kind = UpstreamErrorCanceled
+	case requestCtx.Err() != nil,
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		upstreamErr.Kind = UpstreamErrorTimeout
+	case errors.As(err, &dnsErr):
+		upstreamErr.Kind = UpstreamErrorDNS
+	case isTLSError(err):
+		upstreamErr.Kind = UpstreamErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.As(err, &opErr) && opErr.Op == "dial":
+		upstreamErr.Kind = UpstreamErrorConnection
+	}
+
+	return upstreamErr
+}
+
+func isTLSError(err error) bool {
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certificateVerificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &certificateVerificationErr) ||
+		errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateInvalidErr)
+}
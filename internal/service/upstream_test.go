@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gustapinto/api-gatekeeper/internal/config"
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+)
+
+func doTestBackendRequest(b *Backend, backend config.Backend) (int, error) {
+	route := config.Route{Method: http.MethodGet, BackendPath: "/items"}
+
+	response, err := b.DoRequestToBackendRoute(context.Background(), backend, route, model.BackendRequestParams{
+		Body:        http.NoBody,
+		Headers:     http.Header{},
+		QueryParams: url.Values{},
+	})
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	return response.StatusCode, nil
+}
+
+func TestOutlierDetectionEjectsTargetsOnServerErrors(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	backend := config.Backend{
+		Name:             "outlier",
+		Host:             server.URL,
+		OutlierDetection: config.OutlierDetection{ConsecutiveFailures: 2, EjectionSeconds: 1},
+	}
+	b := NewBackend([]config.Backend{backend}, nil, "")
+	defer b.Close()
+
+	for range 2 {
+		if status, err := doTestBackendRequest(b, backend); err != nil || status != http.StatusInternalServerError {
+			t.Fatalf("expected the failing response to be proxied, got %d, %v", status, err)
+		}
+	}
+
+	if _, err := doTestBackendRequest(b, backend); !errors.Is(err, ErrNoAvailableUpstream) {
+		t.Fatalf("expected the target to be ejected, got %v", err)
+	}
+
+	failing.Store(false)
+	time.Sleep(backend.OutlierDetection.EjectionDuration())
+
+	if status, err := doTestBackendRequest(b, backend); err != nil || status != http.StatusOK {
+		t.Errorf("expected the target to return after the ejection, got %d, %v", status, err)
+	}
+}
+
+func TestOutlierDetectionEjectsTargetsOnConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	closedHost := "http://" + listener.Addr().String()
+	listener.Close()
+
+	backend := config.Backend{
+		Name:             "outlier",
+		Targets:          []config.Target{{Host: closedHost, Weight: 1}, {Host: server.URL, Weight: 1}},
+		OutlierDetection: config.OutlierDetection{ConsecutiveFailures: 2, EjectionSeconds: 60},
+	}
+	b := NewBackend([]config.Backend{backend}, nil, "")
+	defer b.Close()
+
+	// The round-robin alternates between the targets until the closed one is ejected
+	failures := 0
+	for range 4 {
+		if _, err := doTestBackendRequest(b, backend); err != nil {
+			failures++
+		}
+	}
+
+	if failures != 2 {
+		t.Fatalf("expected 2 failed requests before the ejection, got %d", failures)
+	}
+
+	if !b.upstreams.get(backend).targets[0].isEjected() {
+		t.Fatalf("expected the unreachable target to be ejected")
+	}
+
+	for range 4 {
+		if status, err := doTestBackendRequest(b, backend); err != nil || status != http.StatusOK {
+			t.Errorf("expected every request to reach the healthy target, got %d, %v", status, err)
+		}
+	}
+}
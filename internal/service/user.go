@@ -21,8 +21,6 @@ type UserRepository interface {
 	Update(model.UpdateUserParams) (*model.User, error)
 
 	Delete(string) error
-
-	IsAlreadyExistsError(error) bool
 }
 
 type User struct {
@@ -37,16 +35,16 @@ func NewUser(userRepository UserRepository) *User {
 
 func (s User) Create(params model.CreateUserParams) (model.User, error) {
 	if strings.TrimSpace(params.Login) == "" {
-		return model.User{}, errors.New("badparams: login parameter must be present and must not be blank")
+		return model.User{}, newValidationError("login parameter must be present and must not be blank")
 	}
 
 	if strings.TrimSpace(params.Password) == "" {
-		return model.User{}, errors.New("badparams: password parameter must be present and must not be blank")
+		return model.User{}, newValidationError("password parameter must be present and must not be blank")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return model.User{}, errors.New("badparams: failed to encode user password")
+		return model.User{}, errors.New("failed to encode user password")
 	}
 
 	params.Password = string(hashedPassword)
@@ -71,7 +69,7 @@ func (s User) CreateApplicationUser(cfg config.User) error {
 			"api-gatekeeper.manage-users",
 		},
 	})
-	if err != nil && !s.userRepository.IsAlreadyExistsError(err) {
+	if err != nil && !errors.Is(err, ErrConflict) {
 		return err
 	}
 
@@ -80,22 +78,26 @@ func (s User) CreateApplicationUser(cfg config.User) error {
 
 func (s User) Update(params model.UpdateUserParams) (model.User, error) {
 	if strings.TrimSpace(params.ID) == "" {
-		return model.User{}, errors.New("badparams: id parameter must be present and must not be blank")
+		return model.User{}, newValidationError("id parameter must be present and must not be blank")
 	}
 
 	if strings.TrimSpace(params.Login) == "" {
-		return model.User{}, errors.New("badparams: login parameter must be present and must not be blank")
+		return model.User{}, newValidationError("login parameter must be present and must not be blank")
 	}
 
 	if params.Password != nil && *params.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*params.Password), bcrypt.DefaultCost)
 		if err != nil {
-			return model.User{}, errors.New("badparams: failed to encode user password")
+			return model.User{}, errors.New("failed to encode user password")
 		}
 
 		*params.Password = string(hashedPassword)
 	}
 
+	if _, err := s.userRepository.GetByID(params.ID); err != nil {
+		return model.User{}, err
+	}
+
 	user, err := s.userRepository.Update(params)
 	if err != nil {
 		return model.User{}, err
@@ -108,7 +110,7 @@ func (s User) Update(params model.UpdateUserParams) (model.User, error) {
 
 func (s User) Delete(id string) error {
 	if strings.TrimSpace(id) == "" {
-		return errors.New("badparams: id parameter must be present and must not be blank")
+		return newValidationError("id parameter must be present and must not be blank")
 	}
 
 	err := s.userRepository.Delete(id)
@@ -121,7 +123,7 @@ func (s User) Delete(id string) error {
 
 func (u User) GetByID(id string) (model.User, error) {
 	if strings.TrimSpace(id) == "" {
-		return model.User{}, errors.New("badparams: id parameter must be present and must not be blank")
+		return model.User{}, newValidationError("id parameter must be present and must not be blank")
 	}
 
 	user, err := u.userRepository.GetByID(id)
@@ -136,15 +138,30 @@ func (u User) GetAll() ([]model.User, error) {
 	return u.userRepository.GetAll()
 }
 
+// Login Checks the user credentials, an unknown login and a wrong password fail with the same
+// ErrUnauthenticated error and take the same bcrypt time, so logins can not be enumerated
 func (u User) Login(username, password string) (model.User, error) {
 	user, err := u.userRepository.GetByLogin(username)
 	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			compareDummyPassword(password)
+			return model.User{}, ErrUnauthenticated
+		}
+
 		return model.User{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return model.User{}, err
+		return model.User{}, ErrUnauthenticated
 	}
 
 	return *user, nil
 }
+
+// dummyPasswordHash Is a bcrypt hash with the default cost, compared against when the login does
+// not exist so unknown logins are not answered faster than wrong passwords
+const dummyPasswordHash = "$2a$10$hM/AgC5X1cc1GGTBahDVnuPlMd6g37Z4SvT/x1wyvuBBGADOm5Bfy"
+
+func compareDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+}
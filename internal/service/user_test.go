@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gustapinto/api-gatekeeper/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserLogin(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	users := NewUser(fakeUserRepository{users: map[string]model.User{
+		"1": {ID: "1", Login: "alice", Password: string(hashedPassword)},
+	}})
+
+	user, err := users.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.ID != "1" {
+		t.Errorf("expected user 1, got %q", user.ID)
+	}
+
+	if _, err := users.Login("alice", "wrong"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected a wrong password to be unauthenticated, got %v", err)
+	}
+
+	if _, err := users.Login("bob", "secret"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected an unknown login to be unauthenticated, got %v", err)
+	}
+}
+
+func TestDummyPasswordHashUsesTheDefaultCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("expected a valid bcrypt hash, got %v", err)
+	}
+
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("expected the dummy hash to cost as much as the stored passwords, got cost %d", cost)
+	}
+}
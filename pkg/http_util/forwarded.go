@@ -0,0 +1,270 @@
+package httputil
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding Is the client information of a request, resolved from the incoming forwarding
+// headers when they were sent by trusted proxies
+type Forwarding struct {
+	ClientIP string
+	Proto    string
+	Host     string
+
+	// forwardedFor Is the trusted X-Forwarded-For chain, it ends with the request peer
+	forwardedFor []string
+
+	// forwarded Is the trusted RFC 7239 Forwarded elements, it ends with the request peer
+	forwarded []string
+}
+
+// ResolveForwarding Resolves the original client of the request. The incoming X-Forwarded-* and
+// Forwarded headers are only honoured if the peer is one of the trusted proxies, and the chain is
+// walked from right to left until the first untrusted hop, which is the client
+func ResolveForwarding(r *http.Request, trustedProxies []netip.Prefix) Forwarding {
+	peer := ClientIP(r)
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+
+	forwarding := Forwarding{
+		ClientIP: peer,
+		Proto:    proto,
+		Host:     r.Host,
+	}
+
+	ownElement := forwardedElement(peer, r.Host, proto)
+
+	if !isTrustedProxy(peer, trustedProxies) {
+		forwarding.forwardedFor = []string{peer}
+		forwarding.forwarded = []string{ownElement}
+		return forwarding
+	}
+
+	var hops []string
+	var elements []map[string]string
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		elements = parseForwarded(values)
+		for _, element := range elements {
+			hops = append(hops, element["for"])
+		}
+	} else {
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	}
+
+	first := 0
+	for i := len(hops) - 1; i >= 0; i-- {
+		first = i
+		if !isTrustedProxy(forwardedNodeIP(hops[i]), trustedProxies) {
+			break
+		}
+	}
+
+	if len(hops) > 0 {
+		forwarding.ClientIP = forwardedNodeIP(hops[first])
+	}
+
+	if elements != nil {
+		if len(hops) > 0 {
+			if host := elements[first]["host"]; host != "" {
+				forwarding.Host = host
+			}
+
+			if proto := elements[first]["proto"]; proto == "http" || proto == "https" {
+				forwarding.Proto = proto
+			}
+		}
+	} else {
+		// The leftmost values may be sent by the client, so the ones appended by the nearest
+		// trusted proxy are used
+		if host := lastHeaderValue(r.Header, "X-Forwarded-Host"); host != "" {
+			forwarding.Host = host
+		}
+
+		if proto := strings.ToLower(lastHeaderValue(r.Header, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			forwarding.Proto = proto
+		}
+	}
+
+	for i := first; i < len(hops); i++ {
+		forwarding.forwardedFor = append(forwarding.forwardedFor, forwardedNodeIP(hops[i]))
+
+		if elements != nil {
+			forwarding.forwarded = append(forwarding.forwarded, formatForwardedElement(elements[i]))
+		} else {
+			forwarding.forwarded = append(forwarding.forwarded, forwardedElement(hops[i], "", ""))
+		}
+	}
+
+	forwarding.forwardedFor = append(forwarding.forwardedFor, peer)
+	forwarding.forwarded = append(forwarding.forwarded, ownElement)
+
+	return forwarding
+}
+
+// Headers Returns the forwarding headers that must be sent to the backends, they replace any
+// forwarding header sent by the client
+func (f Forwarding) Headers() http.Header {
+	headers := make(http.Header)
+	if len(f.forwardedFor) == 0 {
+		return headers
+	}
+
+	headers.Set("X-Forwarded-For", strings.Join(f.forwardedFor, ", "))
+	headers.Set("X-Forwarded-Proto", f.Proto)
+	headers.Set("X-Forwarded-Host", f.Host)
+	headers.Set("Forwarded", strings.Join(f.forwarded, ", "))
+
+	return headers
+}
+
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lastHeaderValue Returns the rightmost value of a comma separated header, which may be repeated
+func lastHeaderValue(header http.Header, key string) string {
+	values := header.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	last := values[len(values)-1]
+	if index := strings.LastIndex(last, ","); index >= 0 {
+		last = last[index+1:]
+	}
+
+	return strings.TrimSpace(last)
+}
+
+// forwardedNodeIP Returns the IP of a Forwarded node, like "192.0.2.1:8080" or "[2001:db8::1]",
+// obfuscated and unknown nodes are returned as is
+func forwardedNodeIP(node string) string {
+	node = strings.Trim(node, `"`)
+
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+
+	return node
+}
+
+func forwardedElement(forNode string, host string, proto string) string {
+	element := map[string]string{"for": forNode}
+	if host != "" {
+		element["host"] = host
+	}
+
+	if proto != "" {
+		element["proto"] = proto
+	}
+
+	return formatForwardedElement(element)
+}
+
+func formatForwardedElement(element map[string]string) string {
+	pairs := make([]string, 0, len(element))
+
+	for _, key := range []string{"for", "by", "host", "proto"} {
+		value, exists := element[key]
+		if !exists || value == "" {
+			continue
+		}
+
+		if key == "for" || key == "by" {
+			if addr, err := netip.ParseAddr(forwardedNodeIP(value)); err == nil && addr.Is6() && !strings.HasPrefix(value, "[") {
+				value = "[" + value + "]"
+			}
+		}
+
+		if strings.ContainsAny(value, `:[]",; `) {
+			value = `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+		}
+
+		pairs = append(pairs, key+"="+value)
+	}
+
+	return strings.Join(pairs, ";")
+}
+
+// parseForwarded Parses the RFC 7239 Forwarded header values into its elements, the parameter
+// names are lower cased and the quoted values unquoted
+func parseForwarded(values []string) []map[string]string {
+	var elements []map[string]string
+
+	for _, value := range values {
+		for _, rawElement := range splitQuoted(value, ',') {
+			element := make(map[string]string)
+
+			for _, pair := range splitQuoted(rawElement, ';') {
+				key, value, found := strings.Cut(pair, "=")
+				if !found {
+					continue
+				}
+
+				value = strings.TrimSpace(value)
+				if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+					value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+				}
+
+				element[strings.ToLower(strings.TrimSpace(key))] = value
+			}
+
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
+
+// splitQuoted Splits the value by the separator, ignoring the separators inside quoted strings
+func splitQuoted(value string, separator rune) []string {
+	var parts []string
+	var current strings.Builder
+	quoted, escaped := false, false
+
+	for _, c := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == separator && !quoted:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(c)
+	}
+
+	return append(parts, strings.TrimSpace(current.String()))
+}
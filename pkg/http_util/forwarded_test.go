@@ -0,0 +1,224 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolveForwarding(t *testing.T) {
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		headers       http.Header
+		wantClientIP  string
+		wantHost      string
+		wantProto     string
+		wantXFF       string
+		wantForwarded string
+	}{
+		{
+			name:          "untrusted peer ignores the forwarding headers",
+			remoteAddr:    "203.0.113.7:5000",
+			headers:       http.Header{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Host": {"evil.example"}, "X-Forwarded-Proto": {"https"}},
+			wantClientIP:  "203.0.113.7",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "203.0.113.7",
+			wantForwarded: "for=203.0.113.7;host=api.example.com;proto=http",
+		},
+		{
+			name:          "untrusted ipv6 peer",
+			remoteAddr:    "[2001:db8::1]:5000",
+			headers:       http.Header{"Forwarded": {"for=198.51.100.1"}},
+			wantClientIP:  "2001:db8::1",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "2001:db8::1",
+			wantForwarded: `for="[2001:db8::1]";host=api.example.com;proto=http`,
+		},
+		{
+			name:          "trusted peer without forwarding headers",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{},
+			wantClientIP:  "10.0.0.1",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "10.0.0.1",
+			wantForwarded: "for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:       "trusted x-forwarded-for chain",
+			remoteAddr: "10.0.0.1:5000",
+			headers: http.Header{
+				"X-Forwarded-For":   {"198.51.100.1, 10.0.0.2"},
+				"X-Forwarded-Host":  {"public.example.com"},
+				"X-Forwarded-Proto": {"https"},
+			},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "public.example.com",
+			wantProto:     "https",
+			wantXFF:       "198.51.100.1, 10.0.0.2, 10.0.0.1",
+			wantForwarded: "for=198.51.100.1, for=10.0.0.2, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:       "spoofed prepended x-forwarded values",
+			remoteAddr: "10.0.0.1:5000",
+			headers: http.Header{
+				"X-Forwarded-For":   {"127.0.0.1, 198.51.100.1"},
+				"X-Forwarded-Host":  {"evil.example, public.example.com"},
+				"X-Forwarded-Proto": {"http", "https"},
+			},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "public.example.com",
+			wantProto:     "https",
+			wantXFF:       "198.51.100.1, 10.0.0.1",
+			wantForwarded: "for=198.51.100.1, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "every hop trusted",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			wantClientIP:  "10.0.0.3",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "10.0.0.3, 10.0.0.2, 10.0.0.1",
+			wantForwarded: "for=10.0.0.3, for=10.0.0.2, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:       "forwarded takes precedence over x-forwarded",
+			remoteAddr: "10.0.0.1:5000",
+			headers: http.Header{
+				"Forwarded":       {`for=198.51.100.1;host=public.example.com;proto=https`},
+				"X-Forwarded-For": {"192.0.2.99"},
+			},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "public.example.com",
+			wantProto:     "https",
+			wantXFF:       "198.51.100.1, 10.0.0.1",
+			wantForwarded: "for=198.51.100.1;host=public.example.com;proto=https, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "spoofed prepended forwarded element",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`for=127.0.0.1;host=evil.example;proto=http, for=198.51.100.1;host=public.example.com;proto=https`}},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "public.example.com",
+			wantProto:     "https",
+			wantXFF:       "198.51.100.1, 10.0.0.1",
+			wantForwarded: "for=198.51.100.1;host=public.example.com;proto=https, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "forwarded ipv6 node with port",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`For="[2001:db8::1]:4711"`}},
+			wantClientIP:  "2001:db8::1",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "2001:db8::1, 10.0.0.1",
+			wantForwarded: `for="[2001:db8::1]:4711", for=10.0.0.1;host=api.example.com;proto=http`,
+		},
+		{
+			name:          "trusted ipv6 proxy node",
+			remoteAddr:    "[2001:db8:ffff::1]:5000",
+			headers:       http.Header{"Forwarded": {`for=198.51.100.1, for="[2001:db8:ffff::2]"`}},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "198.51.100.1, 2001:db8:ffff::2, 2001:db8:ffff::1",
+			wantForwarded: `for=198.51.100.1, for="[2001:db8:ffff::2]", for="[2001:db8:ffff::1]";host=api.example.com;proto=http`,
+		},
+		{
+			name:          "forwarded element without for",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`for=198.51.100.1, host=public.example.com;proto=https`}},
+			wantClientIP:  "",
+			wantHost:      "public.example.com",
+			wantProto:     "https",
+			wantXFF:       ", 10.0.0.1",
+			wantForwarded: "host=public.example.com;proto=https, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "obfuscated forwarded node",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`for=_hidden`}},
+			wantClientIP:  "_hidden",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "_hidden, 10.0.0.1",
+			wantForwarded: "for=_hidden, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "unknown forwarded node",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`for=198.51.100.1, for=unknown`}},
+			wantClientIP:  "unknown",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "unknown, 10.0.0.1",
+			wantForwarded: "for=unknown, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+		{
+			name:          "invalid forwarded proto is ignored",
+			remoteAddr:    "10.0.0.1:5000",
+			headers:       http.Header{"Forwarded": {`for=198.51.100.1;proto=javascript`}},
+			wantClientIP:  "198.51.100.1",
+			wantHost:      "api.example.com",
+			wantProto:     "http",
+			wantXFF:       "198.51.100.1, 10.0.0.1",
+			wantForwarded: "for=198.51.100.1;proto=javascript, for=10.0.0.1;host=api.example.com;proto=http",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://api.example.com/items", nil)
+			r.RemoteAddr = test.remoteAddr
+			r.Header = test.headers
+
+			forwarding := ResolveForwarding(r, trustedProxies)
+			if forwarding.ClientIP != test.wantClientIP {
+				t.Errorf("expected client IP %q, got %q", test.wantClientIP, forwarding.ClientIP)
+			}
+
+			if forwarding.Host != test.wantHost {
+				t.Errorf("expected host %q, got %q", test.wantHost, forwarding.Host)
+			}
+
+			if forwarding.Proto != test.wantProto {
+				t.Errorf("expected proto %q, got %q", test.wantProto, forwarding.Proto)
+			}
+
+			headers := forwarding.Headers()
+			if got := headers.Get("X-Forwarded-For"); got != test.wantXFF {
+				t.Errorf("expected X-Forwarded-For %q, got %q", test.wantXFF, got)
+			}
+
+			if got := headers.Get("Forwarded"); got != test.wantForwarded {
+				t.Errorf("expected Forwarded %q, got %q", test.wantForwarded, got)
+			}
+		})
+	}
+}
+
+func TestParseForwardedQuotedValues(t *testing.T) {
+	elements := parseForwarded([]string{`for="[2001:db8::1]:80";host="a,b;c"`, `For=192.0.2.1;by=_proxy`})
+
+	if len(elements) != 2 {
+		t.Fatalf("expected 2 elements, got %d: %v", len(elements), elements)
+	}
+
+	if elements[0]["for"] != "[2001:db8::1]:80" || elements[0]["host"] != "a,b;c" {
+		t.Errorf("unexpected first element %v", elements[0])
+	}
+
+	if elements[1]["for"] != "192.0.2.1" || elements[1]["by"] != "_proxy" {
+		t.Errorf("unexpected second element %v", elements[1])
+	}
+}
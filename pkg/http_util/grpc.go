@@ -0,0 +1,41 @@
+package httputil
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type GRPCCode int
+
+// The gRPC status codes, as defined on https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	GRPCCodeOK                GRPCCode = 0
+	GRPCCodeUnknown           GRPCCode = 2
+	GRPCCodeDeadlineExceeded  GRPCCode = 4
+	GRPCCodePermissionDenied  GRPCCode = 7
+	GRPCCodeResourceExhausted GRPCCode = 8
+	GRPCCodeUnimplemented     GRPCCode = 12
+	GRPCCodeInternal          GRPCCode = 13
+	GRPCCodeUnavailable       GRPCCode = 14
+	GRPCCodeUnauthenticated   GRPCCode = 16
+)
+
+// WriteGRPCError Writes a gRPC "Trailers-Only" error response, the status is sent on the headers
+// with a 200 HTTP status, as required by the gRPC over HTTP/2 protocol
+func WriteGRPCError(w http.ResponseWriter, code GRPCCode, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+
+	if message != "" {
+		w.Header().Set("Grpc-Message", encodeGRPCMessage(message))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// encodeGRPCMessage Percent-encodes the message, as the grpc-message header must be ASCII only
+func encodeGRPCMessage(message string) string {
+	return strings.ReplaceAll(url.QueryEscape(message), "+", "%20")
+}
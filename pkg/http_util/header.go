@@ -0,0 +1,85 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Hop-by-hop headers, as defined by RFC 7230 section 6.1, these are meaningful only for a
+// single transport-level connection and must not be forwarded by proxies
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func RemoveHopByHopHeaders(header http.Header) {
+	if header == nil {
+		return
+	}
+
+	for _, connectionValue := range header.Values("Connection") {
+		for _, field := range strings.Split(connectionValue, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				header.Del(field)
+			}
+		}
+	}
+
+	for _, hopByHopHeader := range hopByHopHeaders {
+		header.Del(hopByHopHeader)
+	}
+}
+
+// RemoveCORSHeaders Removes the CORS response headers, so a single policy reaches the client
+func RemoveCORSHeaders(header http.Header) {
+	for key := range header {
+		if strings.HasPrefix(key, "Access-Control-") {
+			header.Del(key)
+		}
+	}
+}
+
+// CopyHeaders Copies the source headers into the destination, replacing the values the
+// destination already has for the same keys, like the ones set by the middlewares
+func CopyHeaders(dst http.Header, src http.Header) {
+	for key, values := range src {
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// IsUpgradeRequest Reports if the headers ask for a protocol upgrade, like the WebSocket handshake
+func IsUpgradeRequest(header http.Header) bool {
+	if header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, connectionValue := range header.Values("Connection") {
+		for _, field := range strings.Split(connectionValue, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AcceptsTrailers Reports if the client announced that it accepts trailers with "Te: trailers"
+func AcceptsTrailers(header http.Header) bool {
+	for _, teValue := range header.Values("Te") {
+		for _, field := range strings.Split(teValue, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "trailers") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
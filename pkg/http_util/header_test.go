@@ -0,0 +1,43 @@
+package httputil
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestCopyHeadersReplacesDestinationValues(t *testing.T) {
+	dst := http.Header{
+		"X-Request-Id":        {"gatekeeper-id"},
+		"Ratelimit-Remaining": {"9"},
+		"X-Kept":              {"kept"},
+	}
+	src := http.Header{
+		"X-Request-Id": {"backend-id"},
+		"Set-Cookie":   {"a=1", "b=2"},
+	}
+
+	CopyHeaders(dst, src)
+
+	want := http.Header{
+		"X-Request-Id":        {"backend-id"},
+		"Ratelimit-Remaining": {"9"},
+		"X-Kept":              {"kept"},
+		"Set-Cookie":          {"a=1", "b=2"},
+	}
+
+	if len(dst) != len(want) {
+		t.Fatalf("expected %v, got %v", want, dst)
+	}
+
+	for key, values := range want {
+		if !slices.Equal(dst[key], values) {
+			t.Errorf("expected %s=%v, got %v", key, values, dst[key])
+		}
+	}
+
+	src["Set-Cookie"][0] = "changed"
+	if dst.Get("Set-Cookie") != "a=1" {
+		t.Errorf("expected the copied values to not share the source slice")
+	}
+}
@@ -0,0 +1,124 @@
+package httputil
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	// ProblemTypeBlank Is the RFC 7807 type of problems that have no semantics beyond the status
+	ProblemTypeBlank = "about:blank"
+)
+
+var legacyErrors atomic.Bool
+
+// UseLegacyErrors Switches every error response to the legacy {"message": "..."} format, it should
+// be called once on startup
+func UseLegacyErrors(enabled bool) {
+	legacyErrors.Store(enabled)
+}
+
+// Problem Is a RFC 7807 problem details object, the extensions are serialized as top level members
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	RequestID  string
+	Extensions map[string]any
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+6)
+	maps.Copy(members, p.Extensions)
+
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	if p.RequestID != "" {
+		members["request_id"] = p.RequestID
+	}
+
+	return json.Marshal(members)
+}
+
+type requestIDContextKey struct{}
+
+func WithRequestID(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, requestIDContextKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// WriteProblem Writes the problem as an application/problem+json response, or in the legacy
+// format if enabled. The missing type, title, instance and request ID are filled from the request
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = ProblemTypeBlank
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	if r != nil {
+		if problem.Instance == "" {
+			problem.Instance = r.URL.Path
+		}
+
+		if problem.RequestID == "" {
+			problem.RequestID = RequestIDFromContext(r.Context())
+		}
+	}
+
+	if legacyErrors.Load() {
+		writeLegacyError(w, problem)
+		return
+	}
+
+	problemJson, e := json.Marshal(problem)
+	if e != nil {
+		problemJson = []byte("{}")
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(problemJson)
+}
+
+func writeLegacyError(w http.ResponseWriter, problem Problem) {
+	message := problem.Detail
+	if message == "" {
+		message = problem.Title
+	}
+
+	errorJson, e := json.Marshal(ErrorResponse{
+		Message:   message,
+		RequestID: problem.RequestID,
+	})
+	if e != nil {
+		errorJson = []byte("{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(problem.Status)
+	w.Write(errorJson)
+}
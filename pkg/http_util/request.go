@@ -3,43 +3,29 @@ package httputil
 import (
 	"encoding/base64"
 	"errors"
+	"net"
 	"net/http"
 	"strings"
 )
 
-func GetHeadersAsMap(r *http.Request) map[string]string {
-	if r == nil {
-		return nil
-	}
-
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		for _, value := range values {
-			headers[key] = value
-		}
-	}
-
-	return headers
-}
-
-func GetQueryParamsAsMap(r *http.Request) map[string]string {
-	if r == nil {
-		return nil
-	}
+var (
+	ErrMissingAuthorizationToken   = errors.New("missing Authorization token")
+	ErrMalformedAuthorizationToken = errors.New("malformed Authorization token")
+)
 
-	queryParams := make(map[string]string)
-	for key, values := range r.URL.Query() {
-		for _, value := range values {
-			queryParams[key] = value
-		}
+// ClientIP Returns the IP address of the peer that sent the request
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
 
-	return queryParams
+	return host
 }
 
 func ParseBasicAuthorizationToken(token string) (string, string, error) {
 	if token == "" {
-		return "", "", errors.New("badparams: missing Authorization token")
+		return "", "", ErrMissingAuthorizationToken
 	}
 
 	if strings.Contains(token, "Basic") {
@@ -48,12 +34,12 @@ func ParseBasicAuthorizationToken(token string) (string, string, error) {
 
 	decodedToken, err := base64.StdEncoding.DecodeString(token)
 	if err != nil {
-		return "", "", err
+		return "", "", ErrMalformedAuthorizationToken
 	}
 
 	data := strings.Split(string(decodedToken), ":")
 	if len(data) < 2 {
-		return "", "", err
+		return "", "", ErrMalformedAuthorizationToken
 	}
 	login := data[0]
 	password := data[1]
@@ -6,85 +6,102 @@ import (
 )
 
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
-func WriteMethodNotAllowed(w http.ResponseWriter) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: "Method not allowed",
+func WriteMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusMethodNotAllowed,
+		Detail: "Method not allowed",
 	})
-	if e != nil {
-		errorJson = []byte("{}")
-	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	w.Write(errorJson)
+// WriteInternalServerError Writes a generic internal error, the cause must be logged by the
+// caller as it is not exposed to clients
+func WriteInternalServerError(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusInternalServerError,
+		Detail: "An internal error occurred while handling the request",
+	})
 }
 
-func WriteInternalServerError(w http.ResponseWriter, err error) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: err.Error(),
+// WriteGatewayError Writes an error that happened while proxying a request, the message must be
+// safe to be exposed to clients
+func WriteGatewayError(w http.ResponseWriter, r *http.Request, statusCode int, problemType string, message string) {
+	WriteProblem(w, r, Problem{
+		Type:   problemType,
+		Status: statusCode,
+		Detail: message,
 	})
-	if e != nil {
-		errorJson = []byte("{}")
-	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	w.Write(errorJson)
+func WriteBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	})
 }
 
-func WriteBadRequest(w http.ResponseWriter, err error) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: err.Error(),
+func WriteUnauthorized(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusUnauthorized,
 	})
-	if e != nil {
-		errorJson = []byte("{}")
+}
+
+// WriteForbidden Writes a forbidden error, the missing scopes are sent in the "missing_scopes"
+// problem extension
+func WriteForbidden(w http.ResponseWriter, r *http.Request, missingScopes []string) {
+	problem := Problem{
+		Type:   "urn:api-gatekeeper:problem:missing-scopes",
+		Status: http.StatusForbidden,
+		Detail: "You do not have the scopes to access this resource",
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
-	w.Write(errorJson)
+	if len(missingScopes) > 0 {
+		problem.Extensions = map[string]any{
+			"missing_scopes": missingScopes,
+		}
+	}
+
+	WriteProblem(w, r, problem)
 }
 
-func WriteUnauthorized(w http.ResponseWriter) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: "Unauthorized",
+func WriteIPNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, Problem{
+		Type:   "urn:api-gatekeeper:problem:ip-not-allowed",
+		Status: http.StatusForbidden,
+		Detail: "Your IP address is not allowed to access this resource",
 	})
-	if e != nil {
-		errorJson = []byte("{}")
-	}
-
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	w.Write(errorJson)
 }
 
-func WriteForbidden(w http.ResponseWriter) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: "You do not have the scopes to access this resource",
+func WriteNotFound(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusNotFound,
+		Detail: err.Error(),
 	})
-	if e != nil {
-		errorJson = []byte("{}")
-	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusForbidden)
-	w.Write(errorJson)
+func WriteConflict(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusConflict,
+		Detail: err.Error(),
+	})
 }
 
-func WriteUnprocessableEntity(w http.ResponseWriter, err error) {
-	errorJson, e := json.Marshal(ErrorResponse{
-		Message: err.Error(),
+func WriteTooManyRequests(w http.ResponseWriter, r *http.Request, problemType string, detail string) {
+	WriteProblem(w, r, Problem{
+		Type:   problemType,
+		Status: http.StatusTooManyRequests,
+		Detail: detail,
 	})
-	if e != nil {
-		errorJson = []byte("{}")
-	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnprocessableEntity)
-	w.Write(errorJson)
+func WriteUnprocessableEntity(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, Problem{
+		Status: http.StatusUnprocessableEntity,
+		Detail: err.Error(),
+	})
 }
 
 func WriteCreated(w http.ResponseWriter, data any) {
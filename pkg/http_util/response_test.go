@@ -0,0 +1,28 @@
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteInternalServerErrorUsesGenericDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	WriteInternalServerError(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+
+	var problem map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("expected a JSON body, got %q", w.Body.String())
+	}
+
+	if detail := problem["detail"]; detail != "An internal error occurred while handling the request" {
+		t.Errorf("expected the generic detail, got %q", detail)
+	}
+}
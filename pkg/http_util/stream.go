@@ -0,0 +1,48 @@
+package httputil
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const streamBufferSize = 32 * 1024
+
+var streamBufferPool = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, streamBufferSize)
+		return &buffer
+	},
+}
+
+// StreamBody Copies the body to the response writer using a pooled, bounded buffer,
+// flushing every chunk to the client as soon as it is read
+func StreamBody(w http.ResponseWriter, body io.Reader) error {
+	bufferPtr := streamBufferPool.Get().(*[]byte)
+	defer streamBufferPool.Put(bufferPtr)
+
+	buffer := *bufferPtr
+	controller := http.NewResponseController(w)
+
+	for {
+		n, readErr := body.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return err
+			}
+
+			if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+}